@@ -0,0 +1,120 @@
+package benchmark
+
+import (
+	"bufio"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+)
+
+// procSelfStatus holds the memory counters of the process maintained by the kernel
+const procSelfStatus = "/proc/self/status"
+
+// runtimeMemoryMetrics are the runtime/metrics samples read for the accounting, in the order of its fields
+var runtimeMemoryMetrics = []string{
+	"/memory/classes/heap/objects:bytes",  // Live objects and dead ones not yet swept
+	"/memory/classes/heap/unused:bytes",   // Reserved for objects in spans that are in use, but empty
+	"/memory/classes/heap/free:bytes",     // Free spans still backed by memory
+	"/memory/classes/heap/released:bytes", // Free spans returned to the OS
+	"/memory/classes/heap/stacks:bytes",   // Goroutine stacks
+	"/memory/classes/total:bytes",         // Everything the runtime mapped
+	"/gc/heap/goal:bytes",                 // Heap size at which the next collection is triggered
+}
+
+// MemoryAccounting puts the memory the jobs think they allocated next to what the process,
+// the Go runtime and the cgroup actually use. All values are in bytes, 0 if unavailable.
+type MemoryAccounting struct {
+	// Logical allocation of the memory jobs
+	AllocatedBytes int64 `json:"allocated_bytes"` // Sum of the sizes of all allocated blocks
+	MappedBytes    int64 `json:"mapped_bytes"`    // Part of it mapped outside the Go heap
+
+	// Resident memory of the process from /proc/self/status
+	RSSBytes     int64 `json:"rss_bytes"`      // VmRSS, pages of the process in physical memory
+	RSSPeakBytes int64 `json:"rss_peak_bytes"` // VmHWM, the highest RSS since the process started
+	RSSAnonBytes int64 `json:"rss_anon_bytes"` // RssAnon, resident anonymous memory
+	RSSFileBytes int64 `json:"rss_file_bytes"` // RssFile, resident file mappings such as the binary
+
+	// Memory of the Go runtime from runtime/metrics
+	HeapObjectsBytes  int64 `json:"heap_objects_bytes"`
+	HeapUnusedBytes   int64 `json:"heap_unused_bytes"`
+	HeapFreeBytes     int64 `json:"heap_free_bytes"`     // Free, but not yet returned to the OS
+	HeapReleasedBytes int64 `json:"heap_released_bytes"` // Returned to the OS, does not count towards the RSS
+	StacksBytes       int64 `json:"stacks_bytes"`
+	RuntimeTotalBytes int64 `json:"runtime_total_bytes"` // All memory mapped by the runtime, including metadata
+	HeapGoalBytes     int64 `json:"heap_goal_bytes"`
+
+	// Usage of the whole cgroup, including page cache and other processes in the container
+	CgroupUsageBytes int64 `json:"cgroup_usage_bytes"` // memory.current, or memory.usage_in_bytes with cgroup v1
+	CgroupLimitBytes int64 `json:"cgroup_limit_bytes"` // 0 if unlimited
+}
+
+// readProcessMemory reads the resident memory counters of the process, which are given in kB
+func readProcessMemory(accounting *MemoryAccounting) {
+	file, err := os.Open(procSelfStatus)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	fields := map[string]*int64{
+		"VmRSS:":   &accounting.RSSBytes,
+		"VmHWM:":   &accounting.RSSPeakBytes,
+		"RssAnon:": &accounting.RSSAnonBytes,
+		"RssFile:": &accounting.RSSFileBytes,
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines look like "VmRSS:	  123456 kB"
+		line := strings.Fields(scanner.Text())
+		if len(line) < 2 {
+			continue
+		}
+		if field, ok := fields[line[0]]; ok {
+			if kb, err := strconv.ParseInt(line[1], 10, 64); err == nil {
+				*field = kb * 1024
+			}
+		}
+	}
+}
+
+// readRuntimeMemory reads the memory classes of the Go runtime
+func readRuntimeMemory(accounting *MemoryAccounting) {
+	samples := make([]metrics.Sample, len(runtimeMemoryMetrics))
+	for i, name := range runtimeMemoryMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	fields := []*int64{
+		&accounting.HeapObjectsBytes,
+		&accounting.HeapUnusedBytes,
+		&accounting.HeapFreeBytes,
+		&accounting.HeapReleasedBytes,
+		&accounting.StacksBytes,
+		&accounting.RuntimeTotalBytes,
+		&accounting.HeapGoalBytes,
+	}
+	for i, sample := range samples {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			*fields[i] = int64(sample.Value.Uint64())
+		}
+	}
+}
+
+// GetMemoryAccounting reads the logical allocation together with the process, runtime and cgroup memory usage
+func GetMemoryAccounting() MemoryAccounting {
+	memoryBlocksMutex.Lock()
+	accounting := MemoryAccounting{
+		AllocatedBytes: memoryTotal,
+		MappedBytes:    memoryMapped,
+	}
+	memoryBlocksMutex.Unlock()
+
+	readProcessMemory(&accounting)
+	readRuntimeMemory(&accounting)
+	if limit, usage, ok := cgroupMemory(); ok {
+		accounting.CgroupUsageBytes, accounting.CgroupLimitBytes = usage, limit
+	}
+	return accounting
+}
@@ -0,0 +1,71 @@
+package benchmark
+
+import "fmt"
+
+// Memory allocation backends
+const (
+	BackendHeap = "heap" // Byte slices on the Go heap, returned to the OS by the garbage collector
+	BackendMmap = "mmap" // Anonymous mappings outside the Go heap, unmapped as soon as they are freed
+)
+
+// Transparent huge page advice for mmap blocks
+const (
+	HugePagesDefault = ""           // Leave it to the system-wide THP policy
+	HugePagesOn      = "hugepage"   // MADV_HUGEPAGE
+	HugePagesOff     = "nohugepage" // MADV_NOHUGEPAGE
+)
+
+// allocator describes how the data of new blocks is obtained
+type allocator struct {
+	backend   string
+	hugePages string // Only applies to the mmap backend
+	lock      bool   // mlock the blocks, only applies to the mmap backend
+}
+
+// heapAllocator allocates plain Go heap blocks
+var heapAllocator = allocator{backend: BackendHeap}
+
+// allocate returns a new zeroed block of the given size and whether it was mapped outside the Go heap
+func (a allocator) allocate(size int) ([]byte, bool, error) {
+	if a.backend != BackendMmap {
+		return make([]byte, size), false, nil
+	}
+	data, err := mmapBlock(size, a.hugePages, a.lock)
+	return data, err == nil, err
+}
+
+// String describes the allocator in a few words
+func (a allocator) String() string {
+	if a.backend != BackendMmap {
+		return BackendHeap
+	}
+	text := BackendMmap
+	if a.hugePages != HugePagesDefault {
+		text += ", " + a.hugePages
+	}
+	if a.lock {
+		text += ", locked"
+	}
+	return text
+}
+
+// release gives the memory of a block back. Mapped blocks are unmapped right away,
+// heap blocks are left to the garbage collector. The block must no longer be in the pool.
+func (b *memoryBlock) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.mapped && b.data != nil {
+		if err := munmapBlock(b.data); err != nil {
+			fmt.Printf("Warning: failed to unmap memory block %d: %v\n", b.id, err)
+		}
+	}
+	b.data = nil
+}
+
+// releaseBlocks releases dropped blocks, called without holding memoryBlocksMutex
+func releaseBlocks(blocks []*memoryBlock) {
+	for _, block := range blocks {
+		block.release()
+	}
+}
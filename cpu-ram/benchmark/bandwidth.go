@@ -0,0 +1,353 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Names of the STREAM kernels, in the order they run in every round
+const (
+	StreamCopy  = "copy"  // c = a
+	StreamScale = "scale" // b = s*c
+	StreamAdd   = "add"   // c = a + b
+	StreamTriad = "triad" // a = b + s*c
+)
+
+// Bandwidth benchmark settings
+const (
+	defaultStreamArrayMB = 64                    // Per array, large enough to exceed the last-level cache of most hosts
+	streamScalar         = 3.0                   // The scalar s of the scale and triad kernels
+	streamMinKernelTime  = 20 * time.Millisecond // Small arrays are passed over repeatedly for at least this long
+	bytesPerGB           = 1e9                   // Bandwidth is reported in GB/s like STREAM does, in powers of ten
+)
+
+// streamKernelNames lists the kernels in the order they run
+var streamKernelNames = []string{StreamCopy, StreamScale, StreamAdd, StreamTriad}
+
+// streamBytesPerElement is the memory traffic of one element per kernel: copy and scale read one
+// array and write one, add and triad read two and write one
+var streamBytesPerElement = map[string]int{
+	StreamCopy:  16,
+	StreamScale: 16,
+	StreamAdd:   24,
+	StreamTriad: 24,
+}
+
+// ErrBandwidthMemory is returned when the arrays of a bandwidth job do not fit into the cgroup
+var ErrBandwidthMemory = errors.New("bandwidth arrays do not fit into memory")
+
+// BandwidthOptions describes the arrays and threads of a memory bandwidth job
+type BandwidthOptions struct {
+	ArrayMB  int           // Size of each of the three arrays, 0 uses the default of 64 MB
+	Threads  int           // Threads sharing the arrays, 0 uses one per core of the cgroup CPU limit
+	Rounds   int           // Complete the job after this many rounds of all kernels, 0 runs until stopped
+	Duration time.Duration // Stop the job automatically after this long, 0 runs until stopped
+	LeaseTTL time.Duration // Stop the job unless its lease is renewed within this TTL
+}
+
+// KernelBandwidth is the measured bandwidth of one STREAM kernel
+type KernelBandwidth struct {
+	Kernel     string  `json:"kernel"`
+	LastGBps   float64 `json:"last_gbps"`   // Bandwidth of the most recent round
+	BestGBps   float64 `json:"best_gbps"`   // Highest bandwidth of any round, STREAM's reported figure
+	MeanGBps   float64 `json:"mean_gbps"`   // Mean bandwidth over the window
+	StdDevGBps float64 `json:"stddev_gbps"` // Standard deviation over the window
+	DropPct    float64 `json:"drop_pct"`    // How far the last round fell below the best one, a sign of contention
+}
+
+// BandwidthResults summarizes the bandwidth measured by a job
+type BandwidthResults struct {
+	Running      bool              `json:"running"`
+	ArrayMB      int               `json:"array_mb"`
+	Threads      int               `json:"threads"`
+	StartTime    time.Time         `json:"start_time"`
+	WindowRounds int               `json:"window_rounds"` // Rounds the mean is taken over
+	Rounds       int               `json:"rounds"`        // Completed rounds of all kernels
+	Kernels      []KernelBandwidth `json:"kernels"`
+}
+
+// streamArrays holds the three arrays of the STREAM kernels
+type streamArrays struct {
+	a, b, c []float64
+}
+
+// bandwidthJob runs the STREAM kernels over its own arrays until stopped
+type bandwidthJob struct {
+	options BandwidthOptions
+	arrays  streamArrays
+	job     *Job
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex   sync.Mutex
+	results BandwidthResults
+	window  map[string][]float64 // GB/s of the last rounds per kernel, oldest first
+}
+
+// runStreamKernel runs one kernel over the elements [lo, hi) of the arrays
+func runStreamKernel(kernel string, arrays streamArrays, lo, hi int) {
+	a, b, c := arrays.a[lo:hi], arrays.b[lo:hi], arrays.c[lo:hi]
+	switch kernel {
+	case StreamCopy:
+		for i := range c {
+			c[i] = a[i]
+		}
+	case StreamScale:
+		for i := range b {
+			b[i] = streamScalar * c[i]
+		}
+	case StreamAdd:
+		for i := range c {
+			c[i] = a[i] + b[i]
+		}
+	case StreamTriad:
+		for i := range a {
+			a[i] = b[i] + streamScalar*c[i]
+		}
+	}
+}
+
+// newStreamArrays allocates and initializes the arrays, touching every page
+func newStreamArrays(elements int) streamArrays {
+	arrays := streamArrays{
+		a: make([]float64, elements),
+		b: make([]float64, elements),
+		c: make([]float64, elements),
+	}
+	for i := range arrays.a {
+		arrays.a[i], arrays.b[i], arrays.c[i] = 1, 2, 0
+	}
+	return arrays
+}
+
+// pass runs a kernel once over the whole arrays, split evenly between the threads
+func (b *bandwidthJob) pass(kernel string) {
+	elements := len(b.arrays.a)
+	threads := b.options.Threads
+
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		lo, hi := elements*t/threads, elements*(t+1)/threads
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runStreamKernel(kernel, b.arrays, lo, hi)
+		}()
+	}
+	wg.Wait()
+}
+
+// measure passes over the arrays with a kernel for at least streamMinKernelTime and returns its GB/s
+func (b *bandwidthJob) measure(kernel string) float64 {
+	bytesPerPass := float64(len(b.arrays.a) * streamBytesPerElement[kernel])
+	passes := 0
+	start := time.Now()
+	for passes == 0 || time.Since(start) < streamMinKernelTime {
+		b.pass(kernel)
+		passes++
+	}
+	return bytesPerPass * float64(passes) / time.Since(start).Seconds() / bytesPerGB
+}
+
+// record adds the bandwidth of one round of all kernels to the results
+func (b *bandwidthJob) record(round map[string]float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.results.Rounds++
+	for i := range b.results.Kernels {
+		result := &b.results.Kernels[i]
+		gbps := round[result.Kernel]
+		b.window[result.Kernel] = appendWindow(b.window[result.Kernel], gbps)
+
+		result.LastGBps = gbps
+		result.BestGBps = math.Max(result.BestGBps, gbps)
+		result.MeanGBps, result.StdDevGBps = meanStdDev(b.window[result.Kernel])
+		result.DropPct = slowdownPct(gbps, result.BestGBps)
+	}
+}
+
+// run measures rounds of all kernels until stopped or the configured rounds are done
+func (b *bandwidthJob) run() {
+	defer b.wg.Done()
+
+	fmt.Printf("Bandwidth job %s started - 3 arrays of %d MB, %d threads\n",
+		b.job.id, b.options.ArrayMB, b.options.Threads)
+
+	for rounds := 0; b.options.Rounds == 0 || rounds < b.options.Rounds; rounds++ {
+		round := make(map[string]float64, len(streamKernelNames))
+		for _, kernel := range streamKernelNames {
+			select {
+			case <-b.stopChan:
+				b.finish()
+				return
+			default:
+			}
+			round[kernel] = b.measure(kernel)
+		}
+		b.record(round)
+	}
+
+	b.finish()
+	b.job.complete(fmt.Sprintf("%d rounds measured", b.options.Rounds))
+}
+
+// finish marks the results as final and drops the arrays, so they can be garbage collected
+func (b *bandwidthJob) finish() {
+	b.mutex.Lock()
+	b.results.Running = false
+	b.mutex.Unlock()
+
+	b.arrays = streamArrays{}
+	fmt.Printf("Bandwidth job %s stopped: %s\n", b.job.id, b.summary())
+}
+
+// stop signals the bandwidth job to stop and waits for the running kernel to finish
+func (b *bandwidthJob) stop() {
+	select {
+	case b.stopChan <- true:
+	default:
+	}
+	b.wg.Wait()
+}
+
+// snapshot returns a copy of the current results
+func (b *bandwidthJob) snapshot() BandwidthResults {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	results := b.results
+	results.Kernels = append([]KernelBandwidth(nil), results.Kernels...)
+	return results
+}
+
+// summary describes the measured bandwidth in one line
+func (b *bandwidthJob) summary() string {
+	results := b.snapshot()
+	text := fmt.Sprintf("STREAM over 3 x %d MB with %d threads", results.ArrayMB, results.Threads)
+	if results.Rounds == 0 {
+		return text + ", first round running"
+	}
+	text += ":"
+	for _, kernel := range results.Kernels {
+		text += fmt.Sprintf(" %s %.2f", kernel.Kernel, kernel.LastGBps)
+	}
+	return text + fmt.Sprintf(" GB/s (round %d)", results.Rounds)
+}
+
+// details describes the live state of the bandwidth job for job views
+func (b *bandwidthJob) details() map[string]interface{} {
+	results := b.snapshot()
+	details := map[string]interface{}{
+		"array_mb": results.ArrayMB,
+		"threads":  results.Threads,
+		"rounds":   results.Rounds,
+	}
+	for _, kernel := range results.Kernels {
+		details[kernel.Kernel+"_gbps"] = kernel.LastGBps
+		details[kernel.Kernel+"_best_gbps"] = kernel.BestGBps
+	}
+	return details
+}
+
+// StartBandwidth starts a job that measures the memory bandwidth with the STREAM copy, scale,
+// add and triad kernels. The arrays are allocated by the job and are not part of the allocated memory.
+// Returns the ID of the new job, ErrCalibrating while a calibration runs, or ErrBandwidthMemory
+// if the arrays would not fit into memory.
+func StartBandwidth(options BandwidthOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	if options.ArrayMB <= 0 {
+		options.ArrayMB = defaultStreamArrayMB
+	}
+	if options.Threads <= 0 {
+		options.Threads = int(math.Ceil(GetCgroupLimits().CPULimit))
+		if options.Threads < 1 {
+			options.Threads = 1
+		}
+	}
+
+	// The arrays are allocated at once, so they must fit into the cgroup or, without a limit, the host
+	size := 3 * int64(options.ArrayMB) * bytesPerMB
+	if _, err := checkCgroupHeadroom(size, 0); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBandwidthMemory, err)
+	}
+	if budget := GetCgroupLimits().MemoryBudgetMB; budget > 0 && size/bytesPerMB > budget/2 {
+		return "", fmt.Errorf("%w: 3 arrays of %d MB exceed half of the %d MB available",
+			ErrBandwidthMemory, options.ArrayMB, budget)
+	}
+
+	runner := &bandwidthJob{
+		options:  options,
+		arrays:   newStreamArrays(options.ArrayMB * bytesPerMB / 8),
+		stopChan: make(chan bool, 1),
+		window:   make(map[string][]float64),
+	}
+	runner.results = BandwidthResults{
+		Running:      true,
+		ArrayMB:      options.ArrayMB,
+		Threads:      options.Threads,
+		StartTime:    time.Now(),
+		WindowRounds: scoreWindow,
+	}
+	for _, kernel := range streamKernelNames {
+		runner.results.Kernels = append(runner.results.Kernels, KernelBandwidth{Kernel: kernel})
+	}
+
+	runner.job = registerJob(JobKindBandwidth, map[string]interface{}{
+		"array_mb":  options.ArrayMB,
+		"threads":   options.Threads,
+		"rounds":    options.Rounds,
+		"duration":  options.Duration.String(),
+		"lease_ttl": options.LeaseTTL.String(),
+	}, runner)
+
+	runner.wg.Add(1)
+	go runner.run()
+	runner.job.armTimers(options.Duration, options.LeaseTTL)
+
+	return runner.job.id, nil
+}
+
+// StreamKernelNames returns the names of the STREAM kernels in the order they run and are reported
+func StreamKernelNames() []string {
+	return append([]string(nil), streamKernelNames...)
+}
+
+// StopBandwidth stops all running bandwidth jobs
+// Returns true if any job was stopped, false if none was running
+func StopBandwidth() bool {
+	return StopJobsOfKind(JobKindBandwidth) > 0
+}
+
+// GetBandwidthResults returns the bandwidth measured by a job.
+// An empty job ID selects the most recently started bandwidth job.
+// Returns false if there is no such job.
+func GetBandwidthResults(jobID string) (BandwidthResults, bool) {
+	var job *Job
+	if jobID == "" {
+		all := jobsOfKind(JobKindBandwidth, false)
+		if len(all) == 0 {
+			return BandwidthResults{}, false
+		}
+		job = all[len(all)-1]
+	} else {
+		var ok bool
+		if job, ok = lookupJob(jobID); !ok {
+			return BandwidthResults{}, false
+		}
+	}
+
+	runner, ok := job.runner.(*bandwidthJob)
+	if !ok {
+		return BandwidthResults{}, false
+	}
+	return runner.snapshot(), true
+}
@@ -0,0 +1,146 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// calibrationDuration is how long each kernel runs on a single core during calibration
+const calibrationDuration = 2 * time.Second
+
+// Calibration is the idle single-core throughput of a kernel, used as the interference baseline
+type Calibration struct {
+	Kernel       string    `json:"kernel"`
+	OpsPerSecond float64   `json:"ops_per_second"`
+	CalibratedAt time.Time `json:"calibrated_at"`
+}
+
+// Errors returned when calibration cannot run
+var (
+	ErrCalibrationBusy = errors.New("calibration is already in progress")
+	ErrJobsRunning     = errors.New("benchmark jobs are running, calibration needs an idle state")
+	ErrCalibrating     = errors.New("calibration is in progress, no job can start until it finished")
+)
+
+// Global calibration state
+var (
+	calibrations     map[string]Calibration
+	calibrating      bool
+	calibrationMutex sync.Mutex
+)
+
+// init initializes the package-level variables
+func init() {
+	calibrations = make(map[string]Calibration)
+}
+
+// measureKernel runs one instance of the kernel for the given duration and returns its ops/s
+func measureKernel(name string, duration time.Duration) float64 {
+	kernel := newCPUKernel(name)
+	kernel() // Warm up caches and lazily allocated buffers
+
+	var ops uint64
+	var result float64
+	start := time.Now()
+	for time.Since(start) < duration {
+		result += kernel()
+		ops++
+	}
+	elapsed := time.Since(start)
+
+	// Use the result to prevent optimization
+	if result == 0.5 {
+		fmt.Println("Unexpected calibration result")
+	}
+	return float64(ops) / elapsed.Seconds()
+}
+
+// Calibrate measures the idle single-core throughput of the given kernels and stores it as the
+// baseline for interference detection. Without kernels, the default kernel is calibrated.
+// Refuses to run next to any job or latency sweep, and new ones are refused with ErrCalibrating until it is done.
+func Calibrate(kernels ...string) ([]Calibration, error) {
+	calibrationMutex.Lock()
+	if calibrating {
+		calibrationMutex.Unlock()
+		return nil, ErrCalibrationBusy
+	}
+	calibrating = true
+	calibrationMutex.Unlock()
+
+	defer func() {
+		calibrationMutex.Lock()
+		calibrating = false
+		calibrationMutex.Unlock()
+	}()
+
+	jobStartMutex.Lock()
+	defer jobStartMutex.Unlock()
+	if CountRunningJobs("") > 0 || IsMeasuringLatency() {
+		return nil, ErrJobsRunning
+	}
+
+	if len(kernels) == 0 {
+		kernels = []string{defaultKernel}
+	}
+
+	results := make([]Calibration, 0, len(kernels))
+	for _, name := range kernels {
+		if !IsValidKernel(name) {
+			return results, fmt.Errorf("unknown kernel %q", name)
+		}
+
+		fmt.Printf("Calibrating %s kernel for %s...\n", name, calibrationDuration)
+		calibration := Calibration{
+			Kernel:       name,
+			OpsPerSecond: measureKernel(name, calibrationDuration),
+			CalibratedAt: time.Now(),
+		}
+		fmt.Printf("Calibrated %s kernel: %.1f ops/s on a single idle core\n", name, calibration.OpsPerSecond)
+
+		calibrationMutex.Lock()
+		calibrations[name] = calibration
+		calibrationMutex.Unlock()
+
+		results = append(results, calibration)
+	}
+
+	return results, nil
+}
+
+// IsCalibrating returns whether a calibration is currently in progress
+func IsCalibrating() bool {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+	return calibrating
+}
+
+// GetCalibrations returns all stored baselines ordered by kernel name
+func GetCalibrations() []Calibration {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+
+	results := make([]Calibration, 0, len(calibrations))
+	for _, calibration := range calibrations {
+		results = append(results, calibration)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Kernel < results[j].Kernel })
+	return results
+}
+
+// getBaseline returns the calibrated ops/s of a kernel, or 0 if it was never calibrated
+func getBaseline(kernel string) float64 {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+	return calibrations[kernel].OpsPerSecond
+}
+
+// slowdownPct returns how much slower the measured throughput is than the baseline, in percent
+func slowdownPct(measured, baseline float64) float64 {
+	if baseline <= 0 || measured <= 0 {
+		return 0
+	}
+	return (1 - measured/baseline) * 100
+}
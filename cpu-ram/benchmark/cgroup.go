@@ -0,0 +1,250 @@
+package benchmark
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cgroup file system locations
+const (
+	cgroupMountPoint = "/sys/fs/cgroup"
+	procSelfCgroup   = "/proc/self/cgroup"
+	cgroupUnlimited  = int64(1) << 62 // cgroup v1 reports "no limit" as a huge page-aligned number
+)
+
+// cgroupVersion returns 2 if the unified hierarchy is mounted at the mount point, 1 otherwise
+func cgroupVersion() int {
+	if _, err := os.Stat(filepath.Join(cgroupMountPoint, "cgroup.controllers")); err == nil {
+		return 2
+	}
+	return 1
+}
+
+// cgroupDir returns the directory of the cgroup the process runs in for a cgroup v1 controller,
+// or the unified cgroup for v2. Inside a container without its own cgroup namespace the path
+// from /proc/self/cgroup does not exist, the container's cgroup is then mounted at the root.
+func cgroupDir(controller string) string {
+	version := cgroupVersion()
+	root := cgroupMountPoint
+	if version == 1 {
+		root = filepath.Join(cgroupMountPoint, controller)
+	}
+
+	file, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return root
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines look like "0::/path" for v2 and "4:memory:/path" for v1
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		match := fields[0] == "0" && fields[1] == ""
+		if version == 1 {
+			match = false
+			for _, name := range strings.Split(fields[1], ",") {
+				match = match || name == controller
+			}
+		}
+		if !match {
+			continue
+		}
+
+		dir := filepath.Join(root, fields[2])
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		return root
+	}
+	return root
+}
+
+// readCgroupValue reads a single-value cgroup file. "max" and v1's "no limit" values (-1 or huge) read as 0.
+func readCgroupValue(dir, name string) (int64, bool) {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, false
+	}
+	text := strings.TrimSpace(string(content))
+	if text == "max" {
+		return 0, true
+	}
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if value < 0 || value >= cgroupUnlimited {
+		return 0, true
+	}
+	return value, true
+}
+
+// cgroupMemory reads the memory limit and usage of the cgroup the process runs in, in bytes.
+// The limit is 0 if there is none. Returns false if no memory controller is available.
+func cgroupMemory() (limit, usage int64, ok bool) {
+	limitFile, usageFile := "memory.max", "memory.current"
+	if cgroupVersion() == 1 {
+		limitFile, usageFile = "memory.limit_in_bytes", "memory.usage_in_bytes"
+	}
+
+	dir := cgroupDir("memory")
+	if limit, ok = readCgroupValue(dir, limitFile); !ok {
+		return 0, 0, false
+	}
+	if usage, ok = readCgroupValue(dir, usageFile); !ok {
+		return 0, 0, false
+	}
+	return limit, usage, true
+}
+
+// cgroupCPUQuota reads the CPU bandwidth limit of the cgroup the process runs in, in cores,
+// and the period it is enforced over. The quota is 0 if there is none.
+func cgroupCPUQuota() (float64, time.Duration, bool) {
+	var quota, period int64
+	if cgroupVersion() == 2 {
+		// cpu.max holds "$MAX $PERIOD", where $MAX may be "max"
+		content, err := os.ReadFile(filepath.Join(cgroupDir("cpu"), "cpu.max"))
+		if err != nil {
+			return 0, 0, false
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) != 2 {
+			return 0, 0, false
+		}
+		if period, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+			return 0, 0, false
+		}
+		if fields[0] != "max" {
+			if quota, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+				return 0, 0, false
+			}
+		}
+	} else {
+		dir := cgroupDir("cpu")
+		var ok bool
+		if quota, ok = readCgroupValue(dir, "cpu.cfs_quota_us"); !ok {
+			return 0, 0, false
+		}
+		if period, ok = readCgroupValue(dir, "cpu.cfs_period_us"); !ok {
+			return 0, 0, false
+		}
+	}
+
+	if period <= 0 {
+		return 0, 0, false
+	}
+	return float64(quota) / float64(period), time.Duration(period) * time.Microsecond, true
+}
+
+// hostMemoryBytes returns the physical memory of the host from /proc/meminfo, 0 if unknown
+func hostMemoryBytes() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The line looks like "MemTotal:       16316412 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// CgroupLimits describes the CPU and memory the container's cgroup grants the server
+type CgroupLimits struct {
+	Version          int     `json:"version"`
+	CPUQuota         float64 `json:"cpu_quota"`         // CPU bandwidth limit in cores, 0 if unlimited
+	CPUPeriod        string  `json:"cpu_period"`        // Period the quota is enforced over
+	CPUs             int     `json:"cpus"`              // CPUs the process may run on
+	CPULimit         float64 `json:"cpu_limit"`         // Cores actually available, the quota or the CPUs if fewer
+	MemoryLimitMB    int64   `json:"memory_limit_mb"`   // Cgroup memory limit, 0 if unlimited
+	MemoryUsageMB    int64   `json:"memory_usage_mb"`   // Current usage of the cgroup, including page cache
+	HostMemoryMB     int64   `json:"host_memory_mb"`    // Physical memory of the host
+	MemoryBudgetMB   int64   `json:"memory_budget_mb"`  // Memory actually available, the cgroup limit or the host memory
+	CPUController    bool    `json:"cpu_controller"`    // Whether the CPU limit could be read
+	MemoryController bool    `json:"memory_controller"` // Whether the memory limit could be read
+}
+
+// GetCgroupLimits reads the current CPU and memory limits of the cgroup the server runs in.
+// Limits can change while the container runs, so they are read again on every call.
+func GetCgroupLimits() CgroupLimits {
+	limits := CgroupLimits{
+		Version:      cgroupVersion(),
+		CPUs:         runtime.NumCPU(),
+		HostMemoryMB: hostMemoryBytes() / bytesPerMB,
+	}
+
+	quota, period, ok := cgroupCPUQuota()
+	limits.CPUController = ok
+	limits.CPUQuota = quota
+	if ok {
+		limits.CPUPeriod = period.String()
+	}
+	limits.CPULimit = float64(limits.CPUs)
+	if quota > 0 && quota < limits.CPULimit {
+		limits.CPULimit = quota
+	}
+
+	memoryLimit, memoryUsage, ok := cgroupMemory()
+	limits.MemoryController = ok
+	limits.MemoryLimitMB = memoryLimit / bytesPerMB
+	limits.MemoryUsageMB = memoryUsage / bytesPerMB
+	limits.MemoryBudgetMB = limits.HostMemoryMB
+	if limits.MemoryLimitMB > 0 && (limits.MemoryBudgetMB == 0 || limits.MemoryLimitMB < limits.MemoryBudgetMB) {
+		limits.MemoryBudgetMB = limits.MemoryLimitMB
+	}
+
+	return limits
+}
+
+// Allocation guard against the cgroup memory limit
+const (
+	cgroupReserve      = 32 * bytesPerMB // Kept free below memory.max for the runtime and the server itself
+	headroomCheckBytes = 64 * bytesPerMB // Allocated at most between two reads of the cgroup usage
+)
+
+// checkCgroupHeadroom returns how much more memory the cgroup can take, up to headroomCheckBytes,
+// or an error if not even size bytes fit. The usage stays below memory.max minus a reserve,
+// and below guardPercent of memory.max if that is set.
+func checkCgroupHeadroom(size int64, guardPercent float64) (int64, error) {
+	limit, usage, ok := cgroupMemory()
+	if !ok || limit == 0 {
+		return headroomCheckBytes, nil
+	}
+
+	ceiling, bound := limit-cgroupReserve, "memory.max"
+	if guardPercent > 0 {
+		if guard := int64(float64(limit) * guardPercent / 100); guard < ceiling {
+			ceiling, bound = guard, fmt.Sprintf("the %.0f%% guard of memory.max", guardPercent)
+		}
+	}
+
+	headroom := ceiling - usage
+	if headroom < size {
+		return 0, fmt.Errorf("cgroup memory usage of %d MB plus %d KB would exceed %s (%d MB limit)",
+			usage/bytesPerMB, size/1024, bound, limit/bytesPerMB)
+	}
+	if headroom > headroomCheckBytes {
+		headroom = headroomCheckBytes
+	}
+	return headroom, nil
+}
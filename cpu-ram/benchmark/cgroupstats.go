@@ -0,0 +1,230 @@
+package benchmark
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroupSampleInterval is how often the cgroup statistics are sampled while jobs run
+const cgroupSampleInterval = time.Second
+
+// CgroupStats is a sample of the kernel's statistics for the container's cgroup.
+// Names are the same for cgroup v1 and v2; counters missing from a version are left out.
+type CgroupStats struct {
+	Time     time.Time         `json:"time"`
+	Counters map[string]uint64 `json:"counters"` // Monotonic counters such as throttled periods and memory events
+	Gauges   map[string]int64  `json:"gauges"`   // Current values such as the memory usage in bytes
+}
+
+// CgroupDelta is the change of the cgroup statistics over a period, e.g. the lifetime of a job
+type CgroupDelta struct {
+	Seconds           float64           `json:"seconds"`             // Length of the period
+	Counters          map[string]uint64 `json:"counters"`            // Increase of every counter
+	MemoryChangeBytes int64             `json:"memory_change_bytes"` // Change of the memory usage
+	MemoryPeakBytes   int64             `json:"memory_peak_bytes"`   // Highest memory usage sampled
+}
+
+// Sampler state, guarded by cgroupStatsMutex
+var (
+	cgroupStatsOnce  sync.Once
+	cgroupStatsMutex sync.Mutex
+	cgroupPrevious   CgroupStats
+	cgroupLatest     CgroupStats
+)
+
+// readKeyValueFile reads a cgroup file of "key value" lines, such as cpu.stat or memory.stat
+func readKeyValueFile(path string) map[string]uint64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// copyValues copies the listed keys of a stat file into the sample under new names, scaled by divisor
+func copyValues(into map[string]uint64, from map[string]uint64, names map[string]string, divisor uint64) {
+	for key, name := range names {
+		if value, ok := from[key]; ok {
+			into[name] = value / divisor
+		}
+	}
+}
+
+// readCgroupStats samples the CPU and memory statistics of the cgroup the process runs in
+func readCgroupStats() CgroupStats {
+	stats := CgroupStats{
+		Time:     time.Now(),
+		Counters: make(map[string]uint64),
+		Gauges:   make(map[string]int64),
+	}
+	cpuDir, memoryDir := cgroupDir("cpu"), cgroupDir("memory")
+
+	if cgroupVersion() == 2 {
+		copyValues(stats.Counters, readKeyValueFile(filepath.Join(cpuDir, "cpu.stat")), map[string]string{
+			"usage_usec":     "cpu_usage_usec",
+			"nr_periods":     "cpu_periods",
+			"nr_throttled":   "cpu_throttled_periods",
+			"throttled_usec": "cpu_throttled_usec",
+		}, 1)
+		copyValues(stats.Counters, readKeyValueFile(filepath.Join(memoryDir, "memory.events")), map[string]string{
+			"high":     "memory_events_high",
+			"max":      "memory_events_max",
+			"oom":      "memory_events_oom",
+			"oom_kill": "memory_events_oom_kill",
+		}, 1)
+		if current, ok := readCgroupValue(memoryDir, "memory.current"); ok {
+			stats.Gauges["memory_current_bytes"] = current
+		}
+	} else {
+		cpuStat := readKeyValueFile(filepath.Join(cpuDir, "cpu.stat"))
+		copyValues(stats.Counters, cpuStat, map[string]string{
+			"nr_periods":   "cpu_periods",
+			"nr_throttled": "cpu_throttled_periods",
+		}, 1)
+		copyValues(stats.Counters, cpuStat, map[string]string{"throttled_time": "cpu_throttled_usec"}, 1000)
+		if usage, ok := readCgroupValue(cgroupDir("cpuacct"), "cpuacct.usage"); ok {
+			stats.Counters["cpu_usage_usec"] = uint64(usage) / 1000
+		}
+		// v1 counts hits of the limit in memory.failcnt and OOM kills in memory.oom_control
+		if failures, ok := readCgroupValue(memoryDir, "memory.failcnt"); ok {
+			stats.Counters["memory_events_max"] = uint64(failures)
+		}
+		copyValues(stats.Counters, readKeyValueFile(filepath.Join(memoryDir, "memory.oom_control")), map[string]string{
+			"oom_kill": "memory_events_oom_kill",
+		}, 1)
+		if current, ok := readCgroupValue(memoryDir, "memory.usage_in_bytes"); ok {
+			stats.Gauges["memory_current_bytes"] = current
+		}
+	}
+
+	// memory.stat shares most counter names, v1 calls anonymous memory rss and the page cache cache
+	memoryStat := readKeyValueFile(filepath.Join(memoryDir, "memory.stat"))
+	copyValues(stats.Counters, memoryStat, map[string]string{
+		"pgfault":                 "memory_pgfault",
+		"pgmajfault":              "memory_pgmajfault",
+		"pgscan":                  "memory_pgscan",
+		"pgsteal":                 "memory_pgsteal",
+		"workingset_refault_anon": "memory_workingset_refault_anon",
+		"workingset_refault_file": "memory_workingset_refault_file",
+	}, 1)
+	for key, name := range map[string]string{"anon": "memory_anon_bytes", "rss": "memory_anon_bytes", "file": "memory_file_bytes", "cache": "memory_file_bytes"} {
+		if value, ok := memoryStat[key]; ok {
+			stats.Gauges[name] = int64(value)
+		}
+	}
+
+	return stats
+}
+
+// diffCgroupStats returns the change between two samples. Counters that went backwards,
+// e.g. because the cgroup was recreated, count as unchanged.
+func diffCgroupStats(from, to CgroupStats, peak int64) CgroupDelta {
+	delta := CgroupDelta{
+		Seconds:           to.Time.Sub(from.Time).Seconds(),
+		Counters:          make(map[string]uint64, len(to.Counters)),
+		MemoryChangeBytes: to.Gauges["memory_current_bytes"] - from.Gauges["memory_current_bytes"],
+		MemoryPeakBytes:   peak,
+	}
+	for name, value := range to.Counters {
+		if start, ok := from.Counters[name]; ok && value >= start {
+			delta.Counters[name] = value - start
+		}
+	}
+	return delta
+}
+
+// jobCgroupUsage follows the cgroup statistics over the lifetime of a job, guarded by the job's mutex
+type jobCgroupUsage struct {
+	start      CgroupStats
+	last       CgroupStats
+	peakMemory int64
+}
+
+// newJobCgroupUsage starts following the statistics from the given sample
+func newJobCgroupUsage(sample CgroupStats) *jobCgroupUsage {
+	return &jobCgroupUsage{start: sample, last: sample, peakMemory: sample.Gauges["memory_current_bytes"]}
+}
+
+// update records a newer sample
+func (u *jobCgroupUsage) update(sample CgroupStats) {
+	u.last = sample
+	if current := sample.Gauges["memory_current_bytes"]; current > u.peakMemory {
+		u.peakMemory = current
+	}
+}
+
+// delta returns the change since the job started, or nil if no statistics are available
+func (u *jobCgroupUsage) delta() *CgroupDelta {
+	if len(u.start.Counters) == 0 && len(u.start.Gauges) == 0 {
+		return nil
+	}
+	delta := diffCgroupStats(u.start, u.last, u.peakMemory)
+	return &delta
+}
+
+// startCgroupSampler starts the background sampler once
+func startCgroupSampler() {
+	cgroupStatsOnce.Do(func() {
+		go runCgroupSampler()
+	})
+}
+
+// runCgroupSampler samples the cgroup statistics every interval while jobs are running
+// and attributes them to every running job
+func runCgroupSampler() {
+	ticker := time.NewTicker(cgroupSampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		running := jobsOfKind("", true)
+		if len(running) == 0 {
+			continue
+		}
+
+		sample := readCgroupStats()
+		cgroupStatsMutex.Lock()
+		cgroupPrevious, cgroupLatest = cgroupLatest, sample
+		cgroupStatsMutex.Unlock()
+
+		for _, job := range running {
+			job.sampleCgroup(sample)
+		}
+	}
+}
+
+// GetCgroupStats reads the current cgroup statistics
+func GetCgroupStats() CgroupStats {
+	return readCgroupStats()
+}
+
+// GetCgroupActivity returns the change of the cgroup statistics over the last sampling interval.
+// Returns false if fewer than two samples were taken in a row, e.g. while no job is running.
+func GetCgroupActivity() (CgroupDelta, bool) {
+	cgroupStatsMutex.Lock()
+	previous, latest := cgroupPrevious, cgroupLatest
+	cgroupStatsMutex.Unlock()
+
+	if previous.Time.IsZero() || latest.Time.Sub(previous.Time) > 2*cgroupSampleInterval ||
+		time.Since(latest.Time) > 2*cgroupSampleInterval {
+		return CgroupDelta{}, false
+	}
+	return diffCgroupStats(previous, latest, latest.Gauges["memory_current_bytes"]), true
+}
@@ -0,0 +1,404 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Churn workload settings
+const (
+	defaultChurnLiveMB    = 256                   // Live set kept by default
+	defaultChurnMinSize   = 16                    // Smallest object in bytes
+	defaultChurnMaxSize   = 32 * 1024             // Largest object in bytes, the largest size class of the Go allocator
+	defaultChurnRateMBps  = 100.0                 // Objects replaced per second by default, in MB
+	defaultChurnLongLived = 0.5                   // Share of the live set that is long-lived
+	churnShortLivedOdds   = 0.9                   // Share of the replacements that hit short-lived objects
+	churnTickInterval     = 10 * time.Millisecond // How often the workload catches up with its rate
+	churnStatsInterval    = time.Second           // How often the GC and heap statistics are refreshed
+	churnFillBatch        = 4096                  // Objects allocated between two checks for a stop while filling
+	churnMaxBurst         = 2 * churnTickInterval // Backlog of the rate skipped instead of caught up with
+)
+
+// MaxChurnObjectSize is the largest object of a churn job, larger ones are not small-object allocations
+const MaxChurnObjectSize = 1024 * 1024
+
+// ErrChurnMemory is returned when the live set of a churn job does not fit into memory
+var ErrChurnMemory = errors.New("churn live set does not fit into memory")
+
+// ChurnOptions describes the live set and the allocation rate of a small-object churn job
+type ChurnOptions struct {
+	LiveMB    int           // Size of the live set, 0 uses the default of 256 MB
+	MinSize   int           // Smallest object in bytes, 0 uses the default of 16 bytes
+	MaxSize   int           // Largest object in bytes, 0 uses the default of 32 KB
+	RateMBps  float64       // Objects replaced per second in MB, 0 uses the default of 100 MB/s
+	LongLived float64       // Share of the live set that is long-lived, 0 uses the default of 0.5
+	Duration  time.Duration // Stop the job automatically after this long, 0 runs until stopped
+	LeaseTTL  time.Duration // Stop the job unless its lease is renewed within this TTL
+}
+
+// ChurnResults describes the live set, allocations and GC behavior of a churn job.
+// GC and heap figures are those of the whole process, other jobs allocating at the same time affect them.
+type ChurnResults struct {
+	Running      bool      `json:"running"`
+	StartTime    time.Time `json:"start_time"`
+	Filled       bool      `json:"filled"` // Whether the live set reached its size
+	LiveObjects  int       `json:"live_objects"`
+	LiveMB       float64   `json:"live_mb"`
+	AllocatedMB  float64   `json:"allocated_mb"`  // Allocated since the start, including the live set
+	Replacements uint64    `json:"replacements"`  // Objects freed and reallocated
+	MeasuredMBps float64   `json:"measured_mbps"` // Allocation rate over the last second
+
+	// Garbage collection since the job started
+	GCCycles       int64   `json:"gc_cycles"`
+	GCPauseTotalMs float64 `json:"gc_pause_total_ms"`
+	GCPauseP50Ms   float64 `json:"gc_pause_p50_ms"` // Over the most recent pauses, at most the last 256
+	GCPauseP99Ms   float64 `json:"gc_pause_p99_ms"`
+	GCPauseMaxMs   float64 `json:"gc_pause_max_ms"`
+
+	// Heap of the process
+	HeapObjectsMB    float64 `json:"heap_objects_mb"`   // Live and not yet swept objects
+	HeapUnusedMB     float64 `json:"heap_unused_mb"`    // Free slots in spans that are in use
+	HeapFreeMB       float64 `json:"heap_free_mb"`      // Free spans not yet returned to the OS
+	FragmentationPct float64 `json:"fragmentation_pct"` // Share of the in-use spans not holding objects
+	OverheadPct      float64 `json:"heap_overhead_pct"` // Heap memory held beyond the live set, relative to it
+}
+
+// churnJob keeps a live set of small objects and keeps replacing them
+type churnJob struct {
+	options ChurnOptions
+	job     *Job
+	rng     *rand.Rand
+
+	// Live set, only used by the run goroutine
+	objects   [][]byte
+	longSlots int // Slots below this index are long-lived
+	liveBytes int64
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex   sync.Mutex
+	results ChurnResults
+	gcStart debug.GCStats // GC statistics when the job started
+}
+
+// objectSize draws an object size from a log-uniform distribution, so small objects are common
+// and every size class is still hit
+func (c *churnJob) objectSize() int {
+	ratio := float64(c.options.MaxSize) / float64(c.options.MinSize)
+	return int(float64(c.options.MinSize) * math.Pow(ratio, c.rng.Float64()))
+}
+
+// newObject allocates an object and writes to it, so its pages are actually backed
+func (c *churnJob) newObject() []byte {
+	object := make([]byte, c.objectSize())
+	object[0] = byte(len(object))
+	object[len(object)-1] = byte(len(object))
+	return object
+}
+
+// replace frees one object of the live set and allocates a new one in its place.
+// Most replacements hit short-lived objects, long-lived ones survive many collections.
+// Returns the size of the new object, 0 if the live set is empty.
+func (c *churnJob) replace() int {
+	slots := len(c.objects)
+	if slots == 0 {
+		return 0
+	}
+	var slot int
+	if c.longSlots == 0 || (c.longSlots < slots && c.rng.Float64() < churnShortLivedOdds) {
+		slot = c.longSlots + c.rng.Intn(slots-c.longSlots)
+	} else {
+		slot = c.rng.Intn(c.longSlots)
+	}
+
+	object := c.newObject()
+	c.liveBytes += int64(len(object) - len(c.objects[slot]))
+	c.objects[slot] = object
+	return len(object)
+}
+
+// fill allocates the live set, checking for a stop between batches. Returns false if stopped.
+func (c *churnJob) fill(target int64) bool {
+	var allocated int64
+	for c.liveBytes < target {
+		select {
+		case <-c.stopChan:
+			return false
+		default:
+		}
+		for i := 0; i < churnFillBatch && c.liveBytes < target; i++ {
+			object := c.newObject()
+			c.objects = append(c.objects, object)
+			c.liveBytes += int64(len(object))
+			allocated += int64(len(object))
+		}
+		c.mutex.Lock()
+		c.results.LiveObjects = len(c.objects)
+		c.results.LiveMB = float64(c.liveBytes) / bytesPerMB
+		c.results.AllocatedMB = float64(allocated) / bytesPerMB
+		c.mutex.Unlock()
+	}
+
+	c.longSlots = int(c.options.LongLived * float64(len(c.objects)))
+	c.mutex.Lock()
+	c.results.Filled = true
+	c.mutex.Unlock()
+	return true
+}
+
+// run fills the live set and then replaces objects at the configured rate until signaled to stop
+func (c *churnJob) run() {
+	defer c.wg.Done()
+
+	fmt.Printf("Churn job %s started - filling a %d MB live set of %d-%d byte objects\n",
+		c.job.id, c.options.LiveMB, c.options.MinSize, c.options.MaxSize)
+	if !c.fill(int64(c.options.LiveMB) * bytesPerMB) {
+		c.finish()
+		return
+	}
+	fmt.Printf("Churn job %s filled %d objects, replacing %.1f MB/s\n", c.job.id, len(c.objects), c.options.RateMBps)
+
+	ticker := time.NewTicker(churnTickInterval)
+	defer ticker.Stop()
+	statsTicker := time.NewTicker(churnStatsInterval)
+	defer statsTicker.Stop()
+
+	filledMB := c.snapshot().AllocatedMB
+	bytesPerSecond := c.options.RateMBps * bytesPerMB
+	startTime := time.Now()
+	var done, allocated, replacements uint64 // Bytes replaced since startTime, and in total
+	lastAllocated, lastTime := uint64(0), startTime
+
+	for {
+		select {
+		case <-c.stopChan:
+			c.finish()
+			return
+
+		case <-ticker.C:
+			// Catch up with the rate, but skip what is more than two ticks late instead of bursting
+			due := uint64(time.Since(startTime).Seconds() * bytesPerSecond)
+			if limit := uint64(bytesPerSecond * churnMaxBurst.Seconds()); due > done && due-done > limit {
+				done = due - limit
+			}
+			for done < due {
+				size := uint64(c.replace())
+				if size == 0 {
+					break // Nothing to replace
+				}
+				done += size
+				allocated += size
+				replacements++
+			}
+
+		case <-statsTicker.C:
+			now := time.Now()
+			rate := float64(allocated-lastAllocated) / bytesPerMB / now.Sub(lastTime).Seconds()
+			lastAllocated, lastTime = allocated, now
+
+			c.mutex.Lock()
+			c.results.LiveObjects = len(c.objects)
+			c.results.LiveMB = float64(c.liveBytes) / bytesPerMB
+			c.results.AllocatedMB = filledMB + float64(allocated)/bytesPerMB
+			c.results.Replacements = replacements
+			c.results.MeasuredMBps = rate
+			c.mutex.Unlock()
+			c.refreshStats()
+		}
+	}
+}
+
+// refreshStats updates the GC pause and heap statistics of the results
+func (c *churnJob) refreshStats() {
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	heap := GetMemoryAccounting()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Pauses are listed newest first, only those since the job started count
+	pauses := []float64{}
+	for i, pause := range stats.Pause {
+		if i >= len(stats.PauseEnd) || stats.PauseEnd[i].Before(c.results.StartTime) {
+			break
+		}
+		pauses = append(pauses, float64(pause)/float64(time.Millisecond))
+	}
+	sort.Float64s(pauses)
+	if len(pauses) > 0 {
+		c.results.GCPauseP50Ms = pauses[len(pauses)/2]
+		c.results.GCPauseP99Ms = pauses[(len(pauses)-1)*99/100]
+		c.results.GCPauseMaxMs = pauses[len(pauses)-1]
+	}
+	c.results.GCCycles = stats.NumGC - c.gcStart.NumGC
+	c.results.GCPauseTotalMs = float64(stats.PauseTotal-c.gcStart.PauseTotal) / float64(time.Millisecond)
+
+	c.results.HeapObjectsMB = float64(heap.HeapObjectsBytes) / bytesPerMB
+	c.results.HeapUnusedMB = float64(heap.HeapUnusedBytes) / bytesPerMB
+	c.results.HeapFreeMB = float64(heap.HeapFreeBytes) / bytesPerMB
+	if spans := heap.HeapObjectsBytes + heap.HeapUnusedBytes; spans > 0 {
+		c.results.FragmentationPct = float64(heap.HeapUnusedBytes) / float64(spans) * 100
+	}
+	if live := c.results.LiveMB; live > 0 {
+		held := c.results.HeapObjectsMB + c.results.HeapUnusedMB + c.results.HeapFreeMB
+		c.results.OverheadPct = (held/live - 1) * 100
+	}
+}
+
+// finish marks the results as final and drops the live set
+func (c *churnJob) finish() {
+	c.refreshStats()
+	c.mutex.Lock()
+	c.results.Running = false
+	c.mutex.Unlock()
+
+	c.objects = nil
+	fmt.Printf("Churn job %s stopped: %s\n", c.job.id, c.summary())
+}
+
+// stop signals the churn job to stop and waits for it
+func (c *churnJob) stop() {
+	select {
+	case c.stopChan <- true:
+	default:
+	}
+	c.wg.Wait()
+}
+
+// snapshot returns a copy of the current results
+func (c *churnJob) snapshot() ChurnResults {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.results
+}
+
+// summary describes the churn workload in one line
+func (c *churnJob) summary() string {
+	results := c.snapshot()
+	if !results.Filled {
+		return fmt.Sprintf("filling live set of %d-%d byte objects: %.0f of %d MB",
+			c.options.MinSize, c.options.MaxSize, results.LiveMB, c.options.LiveMB)
+	}
+	return fmt.Sprintf("%d objects (%.0f MB) live, replacing %.1f MB/s (measured %.1f MB/s) - %d GCs, pauses p50 %.2fms p99 %.2fms max %.2fms, heap overhead %.0f%%",
+		results.LiveObjects, results.LiveMB, c.options.RateMBps, results.MeasuredMBps, results.GCCycles,
+		results.GCPauseP50Ms, results.GCPauseP99Ms, results.GCPauseMaxMs, results.OverheadPct)
+}
+
+// details describes the live state of the churn job for job views
+func (c *churnJob) details() map[string]interface{} {
+	results := c.snapshot()
+	return map[string]interface{}{
+		"live_objects":      results.LiveObjects,
+		"live_mb":           results.LiveMB,
+		"measured_mbps":     results.MeasuredMBps,
+		"replacements":      results.Replacements,
+		"gc_cycles":         results.GCCycles,
+		"gc_pause_p99_ms":   results.GCPauseP99Ms,
+		"gc_pause_max_ms":   results.GCPauseMaxMs,
+		"fragmentation_pct": results.FragmentationPct,
+		"heap_overhead_pct": results.OverheadPct,
+	}
+}
+
+// StartChurn starts a job that keeps a live set of small objects of varied sizes and lifetimes
+// and replaces them at the given rate, to stress the allocator and garbage collector.
+// Returns the ID of the new job, ErrCalibrating while a calibration runs, or ErrChurnMemory
+// if the live set would not fit into memory.
+func StartChurn(options ChurnOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	if options.LiveMB <= 0 {
+		options.LiveMB = defaultChurnLiveMB
+	}
+	if options.MinSize <= 0 {
+		options.MinSize = defaultChurnMinSize
+	}
+	if options.MaxSize <= 0 {
+		options.MaxSize = defaultChurnMaxSize
+	}
+	if options.MaxSize < options.MinSize {
+		options.MaxSize = options.MinSize
+	}
+	if options.RateMBps <= 0 {
+		options.RateMBps = defaultChurnRateMBps
+	}
+	if options.LongLived <= 0 {
+		options.LongLived = defaultChurnLongLived
+	}
+
+	// The heap needs room for the live set plus the garbage the GC lets accumulate before collecting
+	size := int64(options.LiveMB) * bytesPerMB
+	if _, err := checkCgroupHeadroom(size, 0); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrChurnMemory, err)
+	}
+	if budget := GetCgroupLimits().MemoryBudgetMB; budget > 0 && size/bytesPerMB > budget/2 {
+		return "", fmt.Errorf("%w: a %d MB live set exceeds half of the %d MB available",
+			ErrChurnMemory, options.LiveMB, budget)
+	}
+
+	runner := &churnJob{
+		options:  options,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		stopChan: make(chan bool, 1),
+	}
+	runner.results = ChurnResults{Running: true, StartTime: time.Now()}
+	debug.ReadGCStats(&runner.gcStart)
+
+	runner.job = registerJob(JobKindChurn, map[string]interface{}{
+		"live_mb":    options.LiveMB,
+		"min_size":   options.MinSize,
+		"max_size":   options.MaxSize,
+		"rate_mbps":  options.RateMBps,
+		"long_lived": options.LongLived,
+		"duration":   options.Duration.String(),
+		"lease_ttl":  options.LeaseTTL.String(),
+	}, runner)
+
+	runner.wg.Add(1)
+	go runner.run()
+	runner.job.armTimers(options.Duration, options.LeaseTTL)
+
+	return runner.job.id, nil
+}
+
+// StopChurn stops all running churn jobs
+// Returns true if any job was stopped, false if none was running
+func StopChurn() bool {
+	return StopJobsOfKind(JobKindChurn) > 0
+}
+
+// GetChurnResults returns the results of a churn job.
+// An empty job ID selects the most recently started churn job.
+// Returns false if there is no such job.
+func GetChurnResults(jobID string) (ChurnResults, bool) {
+	var job *Job
+	if jobID == "" {
+		all := jobsOfKind(JobKindChurn, false)
+		if len(all) == 0 {
+			return ChurnResults{}, false
+		}
+		job = all[len(all)-1]
+	} else {
+		var ok bool
+		if job, ok = lookupJob(jobID); !ok {
+			return ChurnResults{}, false
+		}
+	}
+
+	runner, ok := job.runner.(*churnJob)
+	if !ok {
+		return ChurnResults{}, false
+	}
+	return runner.snapshot(), true
+}
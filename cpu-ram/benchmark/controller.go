@@ -0,0 +1,142 @@
+package benchmark
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Feedback controller settings
+const (
+	controlInterval = 1 * time.Second // How often achieved utilization is measured
+	controlGain     = 0.5             // Fraction of the observed error corrected per interval
+	minDutyCycle    = 0.01            // Lower bound so workers with a nonzero target never stall completely
+	minCorrection   = 0.1             // Bounds of the correction factor applied to requested duty cycles
+	maxCorrection   = 4.0
+)
+
+// Correction factor and achieved load, stored as bits for lock-free access
+var (
+	cpuCorrection   uint64 // Factor applied to every requested duty cycle
+	cpuAchievedLoad uint64 // Measured load of the CPU workers, in cores
+	cpuUsedNanos    int64  // CPU time all CPU workers ever got while computing, updated atomically
+
+	cpuControllerOnce sync.Once
+)
+
+// init initializes the package-level variables
+func init() {
+	storeCorrection(1.0)
+}
+
+// loadCorrection returns the factor the controller applies to requested duty cycles
+func loadCorrection() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&cpuCorrection))
+}
+
+// storeCorrection stores the factor the controller applies to requested duty cycles
+func storeCorrection(correction float64) {
+	atomic.StoreUint64(&cpuCorrection, math.Float64bits(correction))
+}
+
+// loadAchievedLoad returns the last measured CPU load in cores
+func loadAchievedLoad() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&cpuAchievedLoad))
+}
+
+// storeAchievedLoad stores the last measured CPU load in cores
+func storeAchievedLoad(load float64) {
+	atomic.StoreUint64(&cpuAchievedLoad, math.Float64bits(load))
+}
+
+// correctDuty applies the controller correction to a requested duty cycle,
+// clamped to the range a worker can actually deliver
+func correctDuty(requested float64) float64 {
+	if requested <= 0 {
+		return 0
+	}
+
+	duty := requested * loadCorrection()
+	if duty > 1 {
+		duty = 1
+	} else if duty < minDutyCycle {
+		duty = minDutyCycle
+	}
+	return duty
+}
+
+// workerCPUTime returns the CPU time all CPU workers got so far. Time spent throttled or waiting
+// for a CPU is not included, memory jobs, calibration and the GC do not count either.
+func workerCPUTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&cpuUsedNanos))
+}
+
+// cpuUsageSampler measures how many cores the CPU workers used between two samples
+type cpuUsageSampler struct {
+	lastCPU  time.Duration
+	lastWall time.Time
+}
+
+// newCPUUsageSampler creates a sampler starting from the current worker CPU time
+func newCPUUsageSampler() *cpuUsageSampler {
+	return &cpuUsageSampler{
+		lastCPU:  workerCPUTime(),
+		lastWall: time.Now(),
+	}
+}
+
+// sample returns the average number of cores used since the previous sample
+func (s *cpuUsageSampler) sample() float64 {
+	now := time.Now()
+	cpu := workerCPUTime()
+
+	wall := now.Sub(s.lastWall)
+	used := cpu - s.lastCPU
+	s.lastCPU = cpu
+	s.lastWall = now
+
+	if wall <= 0 {
+		return 0
+	}
+	return float64(used) / float64(wall)
+}
+
+// startCPUController starts the feedback controller the first time a CPU task is created
+func startCPUController() {
+	cpuControllerOnce.Do(func() {
+		go runCPUController()
+	})
+}
+
+// runCPUController measures the CPU time of the CPU workers every interval and adjusts the correction
+// factor so that the achieved load converges on the sum of all requested loads
+func runCPUController() {
+	ticker := time.NewTicker(controlInterval)
+	defer ticker.Stop()
+	sampler := newCPUUsageSampler()
+
+	for range ticker.C {
+		achieved := sampler.sample()
+		storeAchievedLoad(achieved)
+		adjustCorrection(GetCPULoad(), achieved)
+	}
+}
+
+// adjustCorrection nudges the correction factor towards the value that delivers the target load
+func adjustCorrection(target, achieved float64) {
+	// Without load to control, start from scratch next time
+	if target <= 0 {
+		storeCorrection(1.0)
+		return
+	}
+
+	correction := loadCorrection() * (1 + controlGain*(target-achieved)/target)
+	if correction > maxCorrection {
+		correction = maxCorrection
+	} else if correction < minCorrection {
+		correction = minCorrection
+	}
+
+	storeCorrection(correction)
+}
@@ -6,107 +6,258 @@ import (
 	"math/rand"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Global variables to control the CPU benchmark task
+// CPU duty-cycle settings
+const (
+	dutyCyclePeriod     = 100 * time.Millisecond // Length of one busy+sleep slice
+	maxUtilizationPct   = 100.0
+	defaultUtilization  = maxUtilizationPct
+	minUtilizationSlice = time.Millisecond // Busy slices shorter than this are skipped
+)
+
+// Global registry of CPU tasks, used by the feedback controller
 var (
-	cpuTaskRunning bool
-	cpuTaskChan    chan bool
-	cpuTaskWg      sync.WaitGroup
-	cpuTaskMutex   sync.Mutex
-	numCoresUsed   int // Number of CPU cores currently being used
+	cpuTasks      map[*cpuTask]bool
+	cpuTasksMutex sync.Mutex
 )
 
 // init initializes the package-level variables
 func init() {
-	cpuTaskRunning = false
-	cpuTaskChan = make(chan bool, 1) // Buffered channel to prevent blocking
-	numCoresUsed = 0
+	cpuTasks = make(map[*cpuTask]bool)
 	rand.Seed(time.Now().UnixNano())
 }
 
-// performCPUIntensiveMath does CPU-intensive calculations to generate load infinitely
-func performCPUIntensiveMath(stopChan chan bool) float64 {
-	result := 0.0
-	// Generate a random base number
-	base := rand.Float64() * 100
+// cpuWorker holds the stop channel and throughput counters of one CPU worker
+type cpuWorker struct {
+	id        int
+	stopChan  chan bool
+	ops       uint64 // Kernel batches completed, updated atomically
+	busyNanos int64  // Time spent computing, updated atomically
+}
 
-	// Counter to periodically check for stop signal
-	counter := 0
+// cpuResizeRequest asks the running CPU task to change its number of workers.
+// The control loop replies with the resulting worker count.
+type cpuResizeRequest struct {
+	cores int
+	reply chan int
+}
 
-	// Run indefinitely until signaled to stop
-	for {
-		// Check if we need to stop more frequently
-		select {
-		case <-stopChan:
-			return result
-		default:
-			// Continue processing
-		}
+// newCPUWorker creates a worker with a buffered stop channel
+func newCPUWorker(id int) *cpuWorker {
+	return &cpuWorker{id: id, stopChan: make(chan bool, 1)}
+}
+
+// CPUTaskOptions describes how a CPU benchmark task should generate load
+type CPUTaskOptions struct {
+	Cores       int           // Number of workers, 0 uses all available cores
+	Utilization float64       // Busy percentage of each worker in (0, 100], 0 means 100
+	Load        float64       // Total load in cores, overrides Cores and Utilization when set
+	Quota       float64       // Total load as a share of the cgroup CPU limit in (0, 1], overrides Load when set
+	Kernel      string        // Workload kernel, empty uses the floating-point math kernel
+	Duration    time.Duration // Stop the task automatically after this long, 0 runs until stopped
+	LeaseTTL    time.Duration // Stop the task unless its lease is renewed within this TTL, 0 disables the lease
+}
+
+// cpuTask is a set of workers running a kernel at a shared duty cycle.
+// Every CPU job owns one, and so do load profiles and trace replays.
+type cpuTask struct {
+	kernel string
+
+	// Requested utilization of each worker as a fraction [0, 1], stored as bits
+	// so workers can read it without taking the mutex on every slice
+	requestedDuty uint64
+
+	stopChan   chan bool
+	resizeChan chan cpuResizeRequest // Worker count changes for the running task
+	wg         sync.WaitGroup
+
+	mutex   sync.Mutex
+	running bool
+	cores   int // Number of CPU cores currently being used
+
+	throughput throughputTracker
+}
 
-		// Perform expensive math operations
-		x := base + math.Sin(float64(counter)/1000)
-		result += math.Sin(x) * math.Cos(x) * math.Exp(math.Sin(x/5))
-		result += math.Sqrt(math.Abs(result)) + math.Pow(x, 0.5*math.Sin(x))
+// normalize resolves Load, Cores and Utilization into a worker count and a per-worker utilization
+func (options CPUTaskOptions) normalize() (int, float64) {
+	availableCores := runtime.NumCPU()
 
-		// Reset result occasionally to prevent overflow
-		if math.Abs(result) > 1e10 {
-			result = rand.Float64() * 100
+	// A share of the CPU quota is a total load relative to what the container may use
+	if options.Quota > 0 {
+		options.Load = options.Quota * GetCgroupLimits().CPULimit
+	}
+
+	// A total load is spread evenly over the smallest number of workers able to carry it
+	if options.Load > 0 {
+		if options.Load > float64(availableCores) {
+			options.Load = float64(availableCores)
 		}
+		options.Cores = int(math.Ceil(options.Load))
+		options.Utilization = options.Load / float64(options.Cores) * 100
+	}
 
-		// Check if we need to stop periodically
-		counter++
-		if counter%1000 == 0 { // Check more frequently (was 10000)
-			select {
-			case <-stopChan:
-				return result
-			default:
-				// Continue processing
+	cores := options.Cores
+	if cores <= 0 || cores > availableCores {
+		cores = availableCores // Default to all cores, and never exceed them
+	}
+
+	utilization := options.Utilization
+	if utilization <= 0 || utilization > maxUtilizationPct {
+		utilization = defaultUtilization
+	}
+
+	return cores, utilization
+}
+
+// newCPUTask creates a CPU task; its workers do not run until started
+func newCPUTask(cores int, utilization float64, kernel string) *cpuTask {
+	if !IsValidKernel(kernel) {
+		kernel = defaultKernel
+	}
+
+	task := &cpuTask{
+		kernel:     kernel,
+		stopChan:   make(chan bool, 1),
+		resizeChan: make(chan cpuResizeRequest, 1),
+		running:    true,
+		cores:      cores,
+	}
+	task.storeRequestedDuty(utilization / 100)
+	return task
+}
+
+// start runs the workers of the task in the background, once its job is registered
+func (t *cpuTask) start() {
+	cpuTasksMutex.Lock()
+	cpuTasks[t] = true
+	cpuTasksMutex.Unlock()
+	startCPUController()
+
+	t.wg.Add(1)
+	go t.run(t.cores)
+}
+
+// loadRequestedDuty returns the duty cycle requested for the workers
+func (t *cpuTask) loadRequestedDuty() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&t.requestedDuty))
+}
+
+// storeRequestedDuty atomically stores the duty cycle requested for the workers
+func (t *cpuTask) storeRequestedDuty(duty float64) {
+	atomic.StoreUint64(&t.requestedDuty, math.Float64bits(duty))
+}
+
+// effectiveDuty returns the fraction of each slice a worker should spend busy,
+// which is the requested duty cycle corrected by the feedback controller
+func (t *cpuTask) effectiveDuty() float64 {
+	return correctDuty(t.loadRequestedDuty())
+}
+
+// performCPUWork runs batches of the kernel until the busy period has elapsed
+// or a stop signal is received. The CPU time the worker's thread actually got is added to the
+// controller's measurement; without a thread clock the wall-clock busy time is used instead.
+// The second return value reports whether the worker was signaled to stop.
+func performCPUWork(kernel cpuKernel, worker *cpuWorker, busy time.Duration) (float64, bool) {
+	result := 0.0
+	start := time.Now()
+	startCPU, threadClock := threadCPUTime()
+	deadline := start.Add(busy)
+	defer func() {
+		wall := time.Since(start)
+		used := wall
+		if threadClock {
+			if endCPU, ok := threadCPUTime(); ok {
+				used = endCPU - startCPU
 			}
 		}
+		atomic.AddInt64(&worker.busyNanos, int64(wall))
+		atomic.AddInt64(&cpuUsedNanos, int64(used))
+	}()
+
+	for {
+		result = kernel()
+		atomic.AddUint64(&worker.ops, 1)
+
+		// Check if we need to stop after every batch
+		select {
+		case <-worker.stopChan:
+			return result, true
+		default:
+			// Continue processing
+		}
+		if time.Now().After(deadline) {
+			return result, false
+		}
 	}
 }
 
-// startCPUTask runs CPU-intensive calculations continuously until signaled to stop
-func startCPUTask(coreCount int) {
-	defer cpuTaskWg.Done()
+// runDutyCycleSlice performs one busy+sleep slice according to the given duty cycle.
+// Returns the calculation result and whether the worker was signaled to stop.
+func runDutyCycleSlice(kernel cpuKernel, worker *cpuWorker, duty float64) (float64, bool) {
+	busy := time.Duration(duty * float64(dutyCyclePeriod))
 
-	// If coreCount is invalid or zero, use all cores
-	if coreCount <= 0 {
-		coreCount = runtime.NumCPU()
+	result := 0.0
+	if busy >= minUtilizationSlice {
+		var stopped bool
+		result, stopped = performCPUWork(kernel, worker, busy)
+		if stopped {
+			return result, true
+		}
 	}
 
-	// Make sure we don't exceed the number of available cores
-	availableCores := runtime.NumCPU()
-	if coreCount > availableCores {
-		coreCount = availableCores
+	// Sleep for the rest of the slice, but wake up immediately on stop
+	if idle := dutyCyclePeriod - busy; idle >= minUtilizationSlice {
+		timer := time.NewTimer(idle)
+		select {
+		case <-worker.stopChan:
+			timer.Stop()
+			return result, true
+		case <-timer.C:
+		}
 	}
 
-	// Store the number of cores in use - use mutex to ensure visibility across goroutines
-	cpuTaskMutex.Lock()
-	numCoresUsed = coreCount
-	cpuTaskMutex.Unlock()
+	return result, false
+}
+
+// run executes the CPU task's workers continuously until signaled to stop
+func (t *cpuTask) run(coreCount int) {
+	defer t.wg.Done()
 
-	fmt.Printf("CPU benchmark task started - generating load using %d of %d available CPU cores\n",
-		coreCount, availableCores)
+	fmt.Printf("CPU benchmark task started - generating %s load using %d of %d available CPU cores at %.1f%% utilization\n",
+		t.kernel, coreCount, runtime.NumCPU(), t.loadRequestedDuty()*100)
 
 	// Create a ticker for status updates
 	statusTicker := time.NewTicker(10 * time.Second)
 	defer statusTicker.Stop()
 
+	// Create a ticker for the throughput measurement
+	sampleTicker := time.NewTicker(controlInterval)
+	defer sampleTicker.Stop()
+
 	// Create channel for results
 	resultChan := make(chan float64, coreCount) // Make this buffered
 
 	// Function for worker goroutines
-	worker := func(id int, stopChan chan bool) {
+	// Workers stay on their own OS thread, so the thread CPU clock measures them alone
+	runWorker := func(w *cpuWorker) {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		kernel := newCPUKernel(t.kernel)
 		for {
 			select {
-			case <-stopChan:
-				fmt.Printf("Worker %d stopping\n", id)
+			case <-w.stopChan:
+				fmt.Printf("Worker %d stopping\n", w.id)
 				return
 			default:
-				result := performCPUIntensiveMath(stopChan)
+				result, stopped := runDutyCycleSlice(kernel, w, t.effectiveDuty())
+				if stopped {
+					fmt.Printf("Worker %d stopping\n", w.id)
+					return
+				}
 				// Send result but don't block if no one is listening
 				select {
 				case resultChan <- result:
@@ -117,130 +268,331 @@ func startCPUTask(coreCount int) {
 	}
 
 	// Start worker goroutines (limited by coreCount)
-	workerStopChans := make([]chan bool, coreCount)
+	workers := make([]*cpuWorker, coreCount)
 	for i := 0; i < coreCount; i++ {
-		workerStopChans[i] = make(chan bool, 1) // Buffered channel for each worker
-		go worker(i, workerStopChans[i])
+		workers[i] = newCPUWorker(i)
+		go runWorker(workers[i])
+	}
+	nextWorkerID := coreCount
+
+	// stopWorker signals a worker to stop without waiting for it
+	stopWorker := func(w *cpuWorker) {
+		fmt.Printf("Stopping worker %d...\n", w.id)
+		w.stopChan <- true
+		close(w.stopChan)
 	}
 
-	var totalCalcs uint64
+	// Throughput is measured on the controller interval
+	t.throughput.reset(t.kernel, workers)
+
+	var lastResult float64
 
 	// Main control loop
 	for {
 		select {
-		case <-cpuTaskChan:
+		case <-t.stopChan:
 			// Signal all workers to stop
 			fmt.Println("CPU benchmark task received stop signal, shutting down all workers...")
 
-			for i, stopChan := range workerStopChans {
-				fmt.Printf("Stopping worker %d...\n", i)
-				stopChan <- true
-				close(stopChan)
+			for _, w := range workers {
+				stopWorker(w)
 			}
 
-			fmt.Printf("CPU benchmark task stopped after %d calculation cycles\n", totalCalcs)
+			results := t.throughput.results()
+			fmt.Printf("CPU benchmark task stopped after %d %s kernel batches (score %.1f ops/s)\n",
+				results.TotalOps, t.kernel, results.Score)
+			t.throughput.finish()
 			return
 
-		case result := <-resultChan:
-			// Just keep track of calculations and occasionally use the result
-			// to prevent the compiler from optimizing away the work
-			totalCalcs++
-			if totalCalcs%1000 == 0 {
-				// Use the result to prevent optimization
-				fmt.Printf("\rPerformed %d calculation cycles (last result: %.5g)...", totalCalcs, result)
+		case req := <-t.resizeChan:
+			// Add or remove workers without disturbing the others
+			for len(workers) < req.cores {
+				w := newCPUWorker(nextWorkerID)
+				nextWorkerID++
+				workers = append(workers, w)
+				go runWorker(w)
 			}
+			for len(workers) > req.cores {
+				stopWorker(workers[len(workers)-1])
+				t.throughput.retire(workers[len(workers)-1])
+				workers = workers[:len(workers)-1]
+			}
+			coreCount = len(workers)
+			req.reply <- coreCount
+
+		case result := <-resultChan:
+			// Keep the last result to prevent the compiler from optimizing away the work
+			lastResult = result
+
+		case <-sampleTicker.C:
+			t.throughput.sample(workers)
 
 		case <-statusTicker.C:
-			fmt.Printf("\nCPU benchmark running - using %d cores - completed %d calculation cycles so far\n",
-				coreCount, totalCalcs)
+			results := t.throughput.results()
+			fmt.Printf("CPU benchmark running - using %d cores - requested %.2f cores (duty cycle %.1f%%) - %.1f ops/s, score %.1f ops/s (last result: %.5g)\n",
+				coreCount, t.loadRequestedDuty()*float64(coreCount), t.effectiveDuty()*100,
+				results.OpsPerSecond, results.Score, lastResult)
 		}
 	}
 }
 
-// StartTaskWithCores starts the CPU benchmark task using the specified number of cores
-// Returns true if task was started, false if it was already running
-func StartTaskWithCores(cores int) bool {
-	cpuTaskMutex.Lock()
-	defer cpuTaskMutex.Unlock()
+// stop signals the CPU task to stop and waits for it
+func (t *cpuTask) stop() {
+	t.mutex.Lock()
 
-	if cpuTaskRunning {
-		return false
+	if !t.running {
+		t.mutex.Unlock()
+		return
 	}
 
-	// Create a fresh channel for this task
-	cpuTaskChan = make(chan bool, 1)
+	fmt.Println("Sending stop signal to CPU benchmark task...")
 
-	// Set numCoresUsed based on the requested cores
-	if cores <= 0 {
-		numCoresUsed = runtime.NumCPU() // Default to all cores
-	} else {
-		availableCores := runtime.NumCPU()
-		if cores > availableCores {
-			numCoresUsed = availableCores
-		} else {
-			numCoresUsed = cores
-		}
+	// Signal the task to stop
+	select {
+	case t.stopChan <- true:
+		fmt.Println("Stop signal sent successfully")
+	default:
+		fmt.Println("Warning: Channel was full, but proceeding with shutdown")
 	}
 
-	// Start the CPU task with specified core count
-	cpuTaskRunning = true
-	cpuTaskWg.Add(1)
-	go startCPUTask(cores)
+	t.running = false
+
+	// Unlock before waiting to avoid deadlock
+	t.mutex.Unlock()
 
-	return true
+	fmt.Println("Waiting for CPU task to complete shutdown...")
+	t.wg.Wait()
+	fmt.Println("CPU task shutdown complete")
+
+	cpuTasksMutex.Lock()
+	delete(cpuTasks, t)
+	cpuTasksMutex.Unlock()
 }
 
-// StartTask starts the CPU benchmark task using all available cores
-// Returns true if task was started, false if it was already running
-func StartTask() bool {
-	return StartTaskWithCores(0) // 0 means use all available cores
+// isRunning returns whether the task's workers are still running
+func (t *cpuTask) isRunning() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.running
 }
 
-// StopTask stops the CPU benchmark task if it's running
-// Returns true if task was stopped, false if it wasn't running
-func StopTask() bool {
-	cpuTaskMutex.Lock()
+// coreCount returns the number of workers of the task, 0 once it stopped
+func (t *cpuTask) coreCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.running {
+		return 0
+	}
+	return t.cores
+}
+
+// requestedLoad returns the load requested from the task, expressed in cores
+func (t *cpuTask) requestedLoad() float64 {
+	return float64(t.coreCount()) * t.loadRequestedDuty()
+}
 
-	if !cpuTaskRunning {
-		cpuTaskMutex.Unlock()
-		return false
+// resize adds or removes workers without stopping the others.
+// Returns the previous and new worker counts, and false if the task is not running.
+func (t *cpuTask) resize(cores int) (int, int, bool) {
+	availableCores := runtime.NumCPU()
+	if cores <= 0 {
+		cores = 1
+	} else if cores > availableCores {
+		cores = availableCores
 	}
 
-	fmt.Println("Sending stop signal to CPU benchmark task...")
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
-	// Signal the task to stop
-	select {
-	case cpuTaskChan <- true:
-		fmt.Println("Stop signal sent successfully")
-	default:
-		fmt.Println("Warning: Channel was full, but proceeding with shutdown")
+	if !t.running {
+		return 0, 0, false
 	}
 
-	cpuTaskRunning = false
+	// The control loop never takes the task mutex, so it is safe to wait for the reply here
+	previous := t.cores
+	reply := make(chan int, 1)
+	t.resizeChan <- cpuResizeRequest{cores: cores, reply: reply}
+	t.cores = <-reply
+
+	return previous, t.cores, true
+}
 
-	// Reset cores used count
-	numCoresUsed = 0
+// setLoad changes the total load of the task without restarting it.
+// The load is expressed in cores and capped at the number of running workers.
+func (t *cpuTask) setLoad(load float64) {
+	cores := t.coreCount()
+	if cores <= 0 {
+		return
+	}
 
-	// Unlock before waiting to avoid deadlock
-	cpuTaskMutex.Unlock()
+	duty := load / float64(cores)
+	if duty > 1 {
+		duty = 1
+	} else if duty < 0 {
+		duty = 0
+	}
+	t.storeRequestedDuty(duty)
+}
 
-	fmt.Println("Waiting for CPU task to complete shutdown...")
-	cpuTaskWg.Wait()
-	fmt.Println("CPU task shutdown complete")
+// details describes the live state of the CPU task for job views
+func (t *cpuTask) details() map[string]interface{} {
+	results := t.throughput.results()
+	return map[string]interface{}{
+		"cores":              t.coreCount(),
+		"kernel":             t.kernel,
+		"utilization_pct":    t.loadRequestedDuty() * 100,
+		"effective_duty_pct": t.effectiveDuty() * 100,
+		"requested_load":     t.requestedLoad(),
+		"ops_per_second":     results.OpsPerSecond,
+		"score":              results.Score,
+		"score_per_core":     results.ScorePerCore,
+		"slowdown_pct":       results.SlowdownPct,
+	}
+}
+
+// summary describes the CPU task in one line
+func (t *cpuTask) summary() string {
+	results := t.throughput.results()
+	text := fmt.Sprintf("%d cores at %.1f%% utilization (%.2f cores of load, effective duty cycle %.1f%%) - %s kernel, %.1f ops/s, score %.1f ops/s",
+		t.coreCount(), t.loadRequestedDuty()*100, t.requestedLoad(), t.effectiveDuty()*100,
+		t.kernel, results.OpsPerSecond, results.Score)
+	if results.Baseline > 0 {
+		text += fmt.Sprintf(", %.1f%% slowdown", results.SlowdownPct)
+	}
+	return text
+}
+
+// cpuTaskOwner is implemented by jobs that generate CPU load
+type cpuTaskOwner interface {
+	cpuTask() *cpuTask
+}
+
+// cpuJob is a job running a single CPU task
+type cpuJob struct {
+	task *cpuTask
+}
+
+func (j *cpuJob) stop()                           { j.task.stop() }
+func (j *cpuJob) summary() string                 { return j.task.summary() }
+func (j *cpuJob) details() map[string]interface{} { return j.task.details() }
+func (j *cpuJob) cpuTask() *cpuTask               { return j.task }
+
+// StartCPUTask starts a new CPU job with the given options.
+// Any number of CPU jobs can run side by side, their loads add up.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartCPUTask(options CPUTaskOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	cores, utilization := options.normalize()
+	task := newCPUTask(cores, utilization, options.Kernel)
+
+	job := registerJob(JobKindCPU, map[string]interface{}{
+		"cores":       cores,
+		"utilization": utilization,
+		"kernel":      task.kernel,
+		"quota":       options.Quota,
+		"duration":    options.Duration.String(),
+		"lease_ttl":   options.LeaseTTL.String(),
+	}, &cpuJob{task: task})
+	task.start()
+	job.armTimers(options.Duration, options.LeaseTTL)
+
+	return job.id, nil
+}
 
-	return true
+// StopTask stops all running CPU jobs
+// Returns true if any job was stopped, false if none was running
+func StopTask() bool {
+	return StopJobsOfKind(JobKindCPU) > 0
 }
 
-// IsTaskRunning returns the current state of the CPU benchmark task
+// IsTaskRunning returns whether any CPU load is currently being generated,
+// by CPU jobs, load profiles or trace replays
 func IsTaskRunning() bool {
-	cpuTaskMutex.Lock()
-	defer cpuTaskMutex.Unlock()
-	return cpuTaskRunning
+	return len(runningCPUTasks()) > 0
+}
+
+// lookupCPUTask returns the CPU task of a job, and false if the job has none
+func lookupCPUTask(jobID string) (*cpuTask, bool) {
+	job, ok := lookupJob(jobID)
+	if !ok {
+		return nil, false
+	}
+	owner, ok := job.runner.(cpuTaskOwner)
+	if !ok {
+		return nil, false
+	}
+	return owner.cpuTask(), true
+}
+
+// ResizeCPUTask adds or removes workers of a running CPU job without stopping the others.
+// The per-worker utilization is kept, so the total load scales with the worker count.
+// Returns the previous and new worker counts, and false if the job is not a running CPU job.
+func ResizeCPUTask(jobID string, cores int) (int, int, bool) {
+	task, ok := lookupCPUTask(jobID)
+	if !ok {
+		return 0, 0, false
+	}
+
+	previous, current, ok := task.resize(cores)
+	if ok {
+		recordEvent("cpu.resize", map[string]interface{}{"job": jobID, "from": previous, "to": current},
+			"Job %s resized from %d to %d workers", jobID, previous, current)
+	}
+	return previous, current, ok
 }
 
-// GetCPUCoresUsed returns the number of CPU cores currently being used by the benchmark
+// GetCPUCoresUsed returns the number of CPU workers across all running CPU tasks
 func GetCPUCoresUsed() int {
-	cpuTaskMutex.Lock()
-	defer cpuTaskMutex.Unlock()
-	return numCoresUsed
+	cores := 0
+	for _, task := range runningCPUTasks() {
+		cores += task.coreCount()
+	}
+	return cores
+}
+
+// GetCPULoad returns the total CPU load requested across all running CPU tasks, expressed in cores
+func GetCPULoad() float64 {
+	load := 0.0
+	for _, task := range runningCPUTasks() {
+		load += task.requestedLoad()
+	}
+	return load
+}
+
+// GetAchievedCPULoad returns the CPU time the CPU workers actually got during
+// the last controller interval, expressed in cores
+func GetAchievedCPULoad() float64 {
+	return loadAchievedLoad()
+}
+
+// runningCPUTasks returns all CPU tasks whose workers are running
+func runningCPUTasks() []*cpuTask {
+	cpuTasksMutex.Lock()
+	tasks := make([]*cpuTask, 0, len(cpuTasks))
+	for task := range cpuTasks {
+		tasks = append(tasks, task)
+	}
+	cpuTasksMutex.Unlock()
+
+	running := tasks[:0]
+	for _, task := range tasks {
+		if task.isRunning() {
+			running = append(running, task)
+		}
+	}
+	return running
+}
+
+// GetCPUJobCores returns the number of workers of a job generating CPU load,
+// and false if the job has no running CPU task
+func GetCPUJobCores(jobID string) (int, bool) {
+	task, ok := lookupCPUTask(jobID)
+	if !ok || !task.isRunning() {
+		return 0, false
+	}
+	return task.coreCount(), true
 }
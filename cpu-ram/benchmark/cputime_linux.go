@@ -0,0 +1,24 @@
+//go:build linux
+
+package benchmark
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// clockThreadCPUTime is CLOCK_THREAD_CPUTIME_ID, the CPU time consumed by the calling thread
+const clockThreadCPUTime = 3
+
+// threadCPUTime returns the CPU time the calling OS thread consumed so far, excluding time spent
+// throttled by the CFS quota or waiting for a CPU. The caller must be locked to its thread with
+// runtime.LockOSThread for the time to be its own. Returns false if the clock cannot be read.
+func threadCPUTime() (time.Duration, bool) {
+	var ts syscall.Timespec
+	_, _, errno := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockThreadCPUTime, uintptr(unsafe.Pointer(&ts)), 0)
+	if errno != 0 {
+		return 0, false
+	}
+	return time.Duration(ts.Nano()), true
+}
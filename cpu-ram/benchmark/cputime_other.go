@@ -0,0 +1,10 @@
+//go:build !linux
+
+package benchmark
+
+import "time"
+
+// threadCPUTime is not supported outside Linux, workers fall back to the wall-clock busy time
+func threadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,85 @@
+package benchmark
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDirtyMBps is the dirty rate used when none is given
+const defaultDirtyMBps = 100.0
+
+// DirtyOptions describes how fast and in which order allocated pages are rewritten
+type DirtyOptions struct {
+	Owner    string        // Job whose memory is dirtied, empty dirties the blocks of all jobs
+	RateMBps float64       // Pages rewritten per second in MB, 0 uses the default of 100 MB/s
+	Random   bool          // Rewrite randomly chosen pages instead of walking them in order
+	Duration time.Duration // Stop the job automatically after this long, 0 runs until stopped
+	LeaseTTL time.Duration // Stop the job unless its lease is renewed within this TTL
+}
+
+// dirtyJob rewrites pages of the allocated memory to generate a steady dirty-page rate
+type dirtyJob struct {
+	options DirtyOptions
+	walker  *pageWalker
+}
+
+func (d *dirtyJob) stop() { d.walker.stop() }
+
+// summary describes the dirty-page generator in one line
+func (d *dirtyJob) summary() string {
+	target := "all jobs"
+	if d.options.Owner != "" {
+		target = d.options.Owner
+	}
+	order := "sequential"
+	if d.options.Random {
+		order = "random"
+	}
+	return fmt.Sprintf("dirtying %.0f MB of %s at %.1f MB/s in %s order (measured %.1f MB/s)",
+		d.walker.walkedMB(), target, d.options.RateMBps, order, d.walker.measuredRate())
+}
+
+// details describes the live state of the dirty-page generator for job views
+func (d *dirtyJob) details() map[string]interface{} {
+	details := d.walker.details()
+	details["dirtied_mb"] = float64(atomic.LoadUint64(&d.walker.touched)) * pageSize / bytesPerMB
+	return details
+}
+
+// StartDirtyPages starts a job that rewrites allocated pages at the given rate.
+// It works on whatever memory is allocated, including blocks added or released later.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartDirtyPages(options DirtyOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	if options.RateMBps <= 0 {
+		options.RateMBps = defaultDirtyMBps
+	}
+
+	runner := &dirtyJob{
+		options: options,
+		walker:  newPageWalker(options.Owner, 1, options.RateMBps, true, options.Random),
+	}
+	job := registerJob(JobKindDirty, map[string]interface{}{
+		"owner":     options.Owner,
+		"rate_mbps": options.RateMBps,
+		"random":    options.Random,
+		"duration":  options.Duration.String(),
+		"lease_ttl": options.LeaseTTL.String(),
+	}, runner)
+
+	runner.walker.start()
+	job.armTimers(options.Duration, options.LeaseTTL)
+
+	return job.id, nil
+}
+
+// StopDirtyPages stops all running dirty-page generators
+// Returns true if any generator was stopped, false if none was running
+func StopDirtyPages() bool {
+	return StopJobsOfKind(JobKindDirty) > 0
+}
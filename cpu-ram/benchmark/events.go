@@ -0,0 +1,50 @@
+package benchmark
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxEvents is the number of events kept in the history, older ones are dropped
+const maxEvents = 1000
+
+// Event is an entry in the benchmark event history
+type Event struct {
+	Time    time.Time              `json:"time"`
+	Kind    string                 `json:"kind"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Global event history
+var (
+	events      []Event
+	eventsMutex sync.Mutex
+)
+
+// recordEvent appends an event to the history and logs it
+func recordEvent(kind string, data map[string]interface{}, format string, args ...interface{}) {
+	event := Event{
+		Time:    time.Now(),
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		Data:    data,
+	}
+	fmt.Printf("Event %s: %s\n", event.Kind, event.Message)
+
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+
+	events = append(events, event)
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+}
+
+// GetEvents returns a copy of the event history, oldest first
+func GetEvents() []Event {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	return append([]Event(nil), events...)
+}
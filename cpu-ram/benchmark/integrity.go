@@ -0,0 +1,188 @@
+package benchmark
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// crcTable is the Castagnoli polynomial, which most CPUs compute in hardware
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// blockRecord is the manifest entry of a block, kept apart from the block itself
+// so that blocks lost from the pool can be detected
+type blockRecord struct {
+	size     int
+	checksum uint32
+}
+
+// memoryManifest lists the blocks every job should still have, guarded by memoryBlocksMutex.
+// Entries are only removed when memory is freed or released on purpose.
+var memoryManifest map[string]map[uint64]blockRecord
+
+// recordBlock adds a block to the manifest of its job, the caller holds memoryBlocksMutex
+func recordBlock(block *memoryBlock, checksum uint32) {
+	records, ok := memoryManifest[block.owner]
+	if !ok {
+		records = make(map[uint64]blockRecord)
+		memoryManifest[block.owner] = records
+	}
+	records[block.id] = blockRecord{size: len(block.data), checksum: checksum}
+}
+
+// unrecordBlock removes a block released on purpose from the manifest of its job,
+// the caller holds memoryBlocksMutex
+func unrecordBlock(block *memoryBlock) {
+	records := memoryManifest[block.owner]
+	delete(records, block.id)
+	if len(records) == 0 {
+		delete(memoryManifest, block.owner)
+	}
+}
+
+// BlockFault describes a block that failed verification
+type BlockFault struct {
+	ID       uint64 `json:"id"`
+	Owner    string `json:"owner"`
+	SizeKB   int    `json:"size_kb"`
+	Expected uint32 `json:"expected_checksum"`
+	Actual   uint32 `json:"actual_checksum,omitempty"` // Not set for missing blocks
+}
+
+// IntegrityReport is the result of verifying the allocated memory against its checksums
+type IntegrityReport struct {
+	Owner           string       `json:"owner,omitempty"` // Job whose memory was verified, empty for all jobs
+	VerifiedAt      time.Time    `json:"verified_at"`
+	DurationSeconds float64      `json:"duration_seconds"`
+	Blocks          int          `json:"blocks"`          // Blocks expected according to the manifest
+	VerifiedBlocks  int          `json:"verified_blocks"` // Blocks whose checksum matched
+	VerifiedMB      float64      `json:"verified_mb"`
+	ModifiedBlocks  int          `json:"modified_blocks"` // Blocks skipped because a writer changed them on purpose
+	Corrupted       []BlockFault `json:"corrupted"`
+	Missing         []BlockFault `json:"missing"` // Blocks in the manifest that are no longer in the pool
+	Intact          bool         `json:"intact"`
+}
+
+// patternSeed derives the seed of a single block from the seed of its job
+func patternSeed(seed, id uint64) uint64 {
+	return seed ^ (id * 0x9E3779B97F4A7C15)
+}
+
+// fillPattern fills data with a splitmix64 stream, which is deterministic for a seed
+// and does not compress or deduplicate
+func fillPattern(data []byte, seed uint64) {
+	state := seed
+	next := func() uint64 {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	i := 0
+	for ; i+8 <= len(data); i += 8 {
+		binary.LittleEndian.PutUint64(data[i:], next())
+	}
+	if i < len(data) {
+		var tail [8]byte
+		binary.LittleEndian.PutUint64(tail[:], next())
+		copy(data[i:], tail[:])
+	}
+}
+
+// blockChecksum returns the checksum of a block's content
+func blockChecksum(data []byte) uint32 {
+	return crc32.Checksum(data, crcTable)
+}
+
+// VerifyMemory recomputes the checksum of every allocated block of a job, or of all jobs
+// if owner is empty, and compares it with the manifest. Blocks in the manifest that are no
+// longer allocated are reported as missing. Blocks changed by a hot set or dirty-page writer
+// are counted as modified instead of corrupted.
+func VerifyMemory(owner string) IntegrityReport {
+	start := time.Now()
+
+	// Snapshot the pool and manifest, the checksums are computed without holding the lock
+	memoryBlocksMutex.Lock()
+	blocks := make([]*memoryBlock, 0, len(memoryBlocks))
+	for _, block := range memoryBlocks {
+		if owner == "" || block.owner == owner {
+			blocks = append(blocks, block)
+		}
+	}
+	expected := make(map[uint64]BlockFault)
+	for job, records := range memoryManifest {
+		if owner != "" && job != owner {
+			continue
+		}
+		for id, record := range records {
+			expected[id] = BlockFault{ID: id, Owner: job, SizeKB: record.size / 1024, Expected: record.checksum}
+		}
+	}
+	memoryBlocksMutex.Unlock()
+
+	report := IntegrityReport{
+		Owner:      owner,
+		VerifiedAt: start,
+		Blocks:     len(expected),
+		Corrupted:  []BlockFault{},
+		Missing:    []BlockFault{},
+	}
+
+	var verifiedBytes int64
+	for _, block := range blocks {
+		fault, ok := expected[block.id]
+		if !ok {
+			continue // Not in the manifest, there is nothing to compare it with
+		}
+		delete(expected, block.id)
+
+		if atomic.LoadUint32(&block.modified) != 0 {
+			report.ModifiedBlocks++
+			continue
+		}
+
+		block.mutex.RLock()
+		if block.data == nil {
+			block.mutex.RUnlock()
+			continue // Released on purpose after the snapshot was taken
+		}
+		actual, size := blockChecksum(block.data), len(block.data)
+		block.mutex.RUnlock()
+
+		if actual != fault.Expected || size/1024 != fault.SizeKB {
+			fault.Actual = actual
+			report.Corrupted = append(report.Corrupted, fault)
+			continue
+		}
+		report.VerifiedBlocks++
+		verifiedBytes += int64(size)
+	}
+
+	// Whatever is left in the manifest was not found in the pool
+	for _, fault := range expected {
+		report.Missing = append(report.Missing, fault)
+	}
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].ID < report.Missing[j].ID })
+
+	report.VerifiedMB = float64(verifiedBytes) / bytesPerMB
+	report.DurationSeconds = time.Since(start).Seconds()
+	report.Intact = len(report.Corrupted) == 0 && len(report.Missing) == 0
+
+	recordEvent("memory.verify", map[string]interface{}{
+		"job":       owner,
+		"blocks":    report.Blocks,
+		"verified":  report.VerifiedBlocks,
+		"modified":  report.ModifiedBlocks,
+		"corrupted": len(report.Corrupted),
+		"missing":   len(report.Missing),
+		"intact":    report.Intact,
+	}, "Verified %d of %d blocks (%.1f MB): %d corrupted, %d missing, %d modified on purpose",
+		report.VerifiedBlocks, report.Blocks, report.VerifiedMB,
+		len(report.Corrupted), len(report.Missing), report.ModifiedBlocks)
+
+	return report
+}
@@ -0,0 +1,397 @@
+package benchmark
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobState is the lifecycle state of a benchmark job
+type JobState string
+
+// Job states
+const (
+	JobRunning      JobState = "running"
+	JobLimitReached JobState = "limit_reached" // Still running, but a memory job stopped growing at its limit or an allocation failure
+	JobStopped      JobState = "stopped"       // Stopped on request
+	JobExpired      JobState = "expired"       // Stopped because its duration or lease ran out
+	JobCompleted    JobState = "completed"     // Finished on its own, e.g. the end of a trace
+)
+
+// Active returns whether a job in this state has not finished yet
+func (s JobState) Active() bool {
+	return s == JobRunning || s == JobLimitReached
+}
+
+// Job kinds
+const (
+	JobKindCPU       = "cpu"
+	JobKindMemory    = "memory"
+	JobKindProfile   = "profile"
+	JobKindTrace     = "trace"
+	JobKindRelease   = "release"
+	JobKindDirty     = "dirty"
+	JobKindBandwidth = "bandwidth"
+	JobKindChurn     = "churn"
+)
+
+// maxFinishedJobs is the number of finished jobs kept for inspection, older ones are dropped
+const maxFinishedJobs = 100
+
+// jobRunner is the task behind a job
+type jobRunner interface {
+	stop()                           // Stops the task and waits for it to shut down
+	summary() string                 // One-line description for the plain-text status
+	details() map[string]interface{} // Live status for the JSON job view
+}
+
+// Job is one independently identified benchmark activation
+type Job struct {
+	mutex     sync.Mutex
+	id        string
+	kind      string
+	params    map[string]interface{}
+	state     JobState
+	startTime time.Time
+	stopTime  time.Time
+	runner    jobRunner
+
+	// Automatic stop: a fixed deadline and/or a lease that must be renewed
+	deadline  time.Time
+	stopTimer *time.Timer
+	leaseID   string
+
+	// afterExpire runs once the job was stopped by its deadline (lease false) or lease (lease true)
+	afterExpire func(lease bool)
+
+	// Cgroup statistics from the start of the job to its last sample, frozen once it finished
+	cgroup *jobCgroupUsage
+}
+
+// JobInfo is a snapshot of a job for listing and inspection
+type JobInfo struct {
+	ID               string                 `json:"id"`
+	Kind             string                 `json:"kind"`
+	Params           map[string]interface{} `json:"params"`
+	State            JobState               `json:"state"`
+	StartTime        time.Time              `json:"start_time"`
+	StopTime         *time.Time             `json:"stop_time,omitempty"`
+	RemainingSeconds *float64               `json:"remaining_seconds,omitempty"`
+	LeaseID          string                 `json:"lease_id,omitempty"`
+	Summary          string                 `json:"summary"`
+	Details          map[string]interface{} `json:"details,omitempty"`
+	Cgroup           *CgroupDelta           `json:"cgroup,omitempty"` // Change of the cgroup statistics while the job ran
+}
+
+// Global job registry
+var (
+	jobs       map[string]*Job
+	jobOrder   []string // Job IDs in start order
+	jobCounter int
+	jobsMutex  sync.Mutex
+
+	// Held exclusively by calibration for its whole run and shared while a job is started,
+	// so no job can start while the idle baseline is measured
+	jobStartMutex sync.RWMutex
+)
+
+// init initializes the package-level variables
+func init() {
+	jobs = make(map[string]*Job)
+}
+
+// newJobID reserves the ID of a job that is about to be registered,
+// for runners that need to know their ID before they are registered
+func newJobID(kind string) string {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	jobCounter++
+	return fmt.Sprintf("%s-%d", kind, jobCounter)
+}
+
+// beginJobStart keeps calibration from starting while a job is set up and registered.
+// Returns ErrCalibrating instead of waiting while a calibration runs, otherwise endJobStart
+// must be called once the job is working.
+func beginJobStart() error {
+	if !jobStartMutex.TryRLock() {
+		return ErrCalibrating
+	}
+	return nil
+}
+
+// endJobStart lets calibration start again after beginJobStart
+func endJobStart() {
+	jobStartMutex.RUnlock()
+}
+
+// registerJob creates a running job for the given runner and adds it to the registry
+func registerJob(kind string, params map[string]interface{}, runner jobRunner) *Job {
+	return registerJobWithID(newJobID(kind), kind, params, runner)
+}
+
+// registerJobWithID registers a job under an ID reserved with newJobID.
+// Runners must not start working before they are registered, the caller is between beginJobStart and endJobStart.
+func registerJobWithID(id, kind string, params map[string]interface{}, runner jobRunner) *Job {
+	sample := readCgroupStats()
+	startCgroupSampler()
+
+	jobsMutex.Lock()
+	job := &Job{
+		id:        id,
+		kind:      kind,
+		params:    params,
+		state:     JobRunning,
+		startTime: time.Now(),
+		runner:    runner,
+		cgroup:    newJobCgroupUsage(sample),
+	}
+	jobs[job.id] = job
+	jobOrder = append(jobOrder, job.id)
+	pruneFinishedJobsLocked()
+	jobsMutex.Unlock()
+
+	data := map[string]interface{}{"job": job.id}
+	for key, value := range params {
+		data[key] = value
+	}
+	recordEvent(kind+".start", data, "Job %s started: %s", job.id, runner.summary())
+
+	return job
+}
+
+// pruneFinishedJobsLocked drops the oldest finished jobs beyond maxFinishedJobs.
+// Must be called with jobsMutex held.
+func pruneFinishedJobsLocked() {
+	finished := 0
+	for i := len(jobOrder) - 1; i >= 0; i-- {
+		if jobs[jobOrder[i]].State().Active() {
+			continue
+		}
+		finished++
+		if finished > maxFinishedJobs {
+			delete(jobs, jobOrder[i])
+			jobOrder = append(jobOrder[:i], jobOrder[i+1:]...)
+		}
+	}
+}
+
+// armTimers sets up the automatic stop of the job after duration and/or unless its lease is renewed
+func (j *Job) armTimers(duration, leaseTTL time.Duration) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if duration > 0 {
+		j.deadline = time.Now().Add(duration)
+		j.stopTimer = time.AfterFunc(duration, func() {
+			j.expire(false)
+		})
+	}
+	if leaseTTL > 0 {
+		j.leaseID = newLease(j.kind, j.id, leaseTTL, func() {
+			j.expire(true)
+		}).ID
+	}
+}
+
+// reachLimit moves a running job into the limit reached state, it keeps running until it is stopped
+func (j *Job) reachLimit() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.state == JobRunning {
+		j.state = JobLimitReached
+	}
+}
+
+// beginFinish moves a running job into a final state and disarms its timers.
+// Returns false if the job had already finished.
+func (j *Job) beginFinish(state JobState) bool {
+	sample := readCgroupStats()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if !j.state.Active() {
+		return false
+	}
+
+	j.cgroup.update(sample)
+	j.state = state
+	j.stopTime = time.Now()
+	if j.stopTimer != nil {
+		j.stopTimer.Stop()
+		j.stopTimer = nil
+	}
+	j.deadline = time.Time{}
+	cancelLease(j.leaseID)
+
+	return true
+}
+
+// stop stops a running job on request
+func (j *Job) stop() bool {
+	if !j.beginFinish(JobStopped) {
+		return false
+	}
+
+	j.runner.stop()
+	recordEvent(j.kind+".stop", map[string]interface{}{"job": j.id}, "Job %s stopped", j.id)
+	return true
+}
+
+// expire stops a running job whose duration or lease ran out
+func (j *Job) expire(lease bool) {
+	if !j.beginFinish(JobExpired) {
+		return
+	}
+
+	kind, reason := j.kind+".expire", "duration elapsed"
+	if lease {
+		kind, reason = j.kind+".lease_expire", "lease expired"
+	}
+	recordEvent(kind, map[string]interface{}{"job": j.id}, "Job %s stopped: %s", j.id, reason)
+
+	j.runner.stop()
+	if j.afterExpire != nil {
+		j.afterExpire(lease)
+	}
+}
+
+// complete marks a job whose task finished on its own.
+// The runner must already be shutting down, so it is not stopped again.
+func (j *Job) complete(reason string) {
+	if !j.beginFinish(JobCompleted) {
+		return
+	}
+	recordEvent(j.kind+".complete", map[string]interface{}{"job": j.id}, "Job %s completed: %s", j.id, reason)
+}
+
+// sampleCgroup attributes a cgroup statistics sample to the job while it is running
+func (j *Job) sampleCgroup(sample CgroupStats) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.state.Active() {
+		j.cgroup.update(sample)
+	}
+}
+
+// State returns the current state of the job
+func (j *Job) State() JobState {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.state
+}
+
+// info returns a snapshot of the job
+func (j *Job) info() JobInfo {
+	j.mutex.Lock()
+	info := JobInfo{
+		ID:        j.id,
+		Kind:      j.kind,
+		Params:    j.params,
+		State:     j.state,
+		StartTime: j.startTime,
+		LeaseID:   j.leaseID,
+		Cgroup:    j.cgroup.delta(),
+	}
+	if !j.stopTime.IsZero() {
+		stopTime := j.stopTime
+		info.StopTime = &stopTime
+	}
+	if !j.deadline.IsZero() {
+		remaining := time.Until(j.deadline).Seconds()
+		info.RemainingSeconds = &remaining
+	}
+	j.mutex.Unlock()
+
+	// The runner has its own locking, query it without holding the job lock
+	info.Summary = j.runner.summary()
+	info.Details = j.runner.details()
+	return info
+}
+
+// lookupJob returns the job with the given ID
+func lookupJob(id string) (*Job, bool) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	job, ok := jobs[id]
+	return job, ok
+}
+
+// jobsOfKind returns the jobs of the given kind (all kinds if empty) in start order
+func jobsOfKind(kind string, runningOnly bool) []*Job {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	result := make([]*Job, 0, len(jobOrder))
+	for _, id := range jobOrder {
+		job := jobs[id]
+		if kind != "" && job.kind != kind {
+			continue
+		}
+		if runningOnly && !job.State().Active() {
+			continue
+		}
+		result = append(result, job)
+	}
+	return result
+}
+
+// ListJobs returns snapshots of all known jobs in start order
+func ListJobs() []JobInfo {
+	all := jobsOfKind("", false)
+	result := make([]JobInfo, 0, len(all))
+	for _, job := range all {
+		result = append(result, job.info())
+	}
+	return result
+}
+
+// GetJob returns a snapshot of the job with the given ID
+func GetJob(id string) (JobInfo, bool) {
+	job, ok := lookupJob(id)
+	if !ok {
+		return JobInfo{}, false
+	}
+	return job.info(), true
+}
+
+// StopJob stops the job with the given ID
+// Returns false if the job does not exist or is no longer running
+func StopJob(id string) bool {
+	job, ok := lookupJob(id)
+	if !ok {
+		return false
+	}
+	return job.stop()
+}
+
+// StopJobsOfKind stops all running jobs of the given kind
+// Returns the number of jobs that were stopped
+func StopJobsOfKind(kind string) int {
+	stopped := 0
+	for _, job := range jobsOfKind(kind, true) {
+		if job.stop() {
+			stopped++
+		}
+	}
+	return stopped
+}
+
+// CountRunningJobs returns the number of running jobs of the given kind (all kinds if empty)
+func CountRunningJobs(kind string) int {
+	return len(jobsOfKind(kind, true))
+}
+
+// JobIDs returns the IDs of the running jobs of the given kind in start order
+func JobIDs(kind string) []string {
+	running := jobsOfKind(kind, true)
+	ids := make([]string, 0, len(running))
+	for _, job := range running {
+		ids = append(ids, job.id)
+	}
+	return ids
+}
@@ -0,0 +1,224 @@
+package benchmark
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Names of the selectable CPU workload kernels
+const (
+	KernelMath     = "math"     // Floating-point trigonometry, exponentials and powers
+	KernelInteger  = "integer"  // Integer multiply, divide, shift and xor arithmetic
+	KernelSHA256   = "sha256"   // SHA-256 hashing of a small buffer
+	KernelCompress = "compress" // Flate compression of semi-random text
+	KernelSort     = "sort"     // Branch-heavy sorting of random integers
+	KernelCache    = "cache"    // Dependent random reads over a buffer larger than the caches
+	KernelMatrix   = "matrix"   // Dense float64 matrix multiplication
+
+	defaultKernel = KernelMath
+)
+
+// Kernel sizing, chosen so one batch takes roughly 10-200 microseconds
+const (
+	mathBatchSize     = 1000
+	integerBatchSize  = 1000
+	sha256BufferSize  = 4 * 1024
+	compressInputSize = 16 * 1024
+	sortBatchSize     = 2048
+	cacheBufferSize   = 64 * 1024 * 1024 // Shared by all workers, read only
+	cacheBatchSize    = 4096
+	matrixSize        = 64
+)
+
+// cpuKernel performs one batch of work and returns a value that depends on it,
+// so the compiler cannot optimize the work away
+type cpuKernel func() float64
+
+// cpuKernels maps kernel names to constructors creating per-worker kernel state
+var cpuKernels = map[string]func() cpuKernel{
+	KernelMath:     newMathKernel,
+	KernelInteger:  newIntegerKernel,
+	KernelSHA256:   newSHA256Kernel,
+	KernelCompress: newCompressKernel,
+	KernelSort:     newSortKernel,
+	KernelCache:    newCacheKernel,
+	KernelMatrix:   newMatrixKernel,
+}
+
+// Buffer shared by all cache kernel workers, allocated on first use
+var (
+	cacheBuffer     []uint32
+	cacheBufferOnce sync.Once
+)
+
+// IsValidKernel returns whether a kernel with the given name exists
+func IsValidKernel(name string) bool {
+	_, ok := cpuKernels[name]
+	return ok
+}
+
+// KernelNames returns the names of all selectable kernels in alphabetical order
+func KernelNames() []string {
+	names := make([]string, 0, len(cpuKernels))
+	for name := range cpuKernels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCPUKernel creates a fresh instance of the named kernel, falling back to the default
+func newCPUKernel(name string) cpuKernel {
+	constructor, ok := cpuKernels[name]
+	if !ok {
+		constructor = cpuKernels[defaultKernel]
+	}
+	return constructor()
+}
+
+// newMathKernel exercises the floating-point unit with sin/cos/exp/pow
+func newMathKernel() cpuKernel {
+	result := 0.0
+	// Generate a random base number
+	base := rand.Float64() * 100
+	counter := 0
+
+	return func() float64 {
+		for i := 0; i < mathBatchSize; i++ {
+			// Perform expensive math operations
+			x := base + math.Sin(float64(counter)/1000)
+			result += math.Sin(x) * math.Cos(x) * math.Exp(math.Sin(x/5))
+			result += math.Sqrt(math.Abs(result)) + math.Pow(x, 0.5*math.Sin(x))
+
+			// Reset result occasionally to prevent overflow
+			if math.Abs(result) > 1e10 {
+				result = rand.Float64() * 100
+			}
+			counter++
+		}
+		return result
+	}
+}
+
+// newIntegerKernel exercises the integer ALUs with xorshift, multiply and divide
+func newIntegerKernel() cpuKernel {
+	state := rand.Uint64() | 1
+	var acc uint64
+
+	return func() float64 {
+		for i := 0; i < integerBatchSize; i++ {
+			state ^= state << 13
+			state ^= state >> 7
+			state ^= state << 17
+			acc += state*2654435761 + state/(uint64(i)|1)%1000003
+		}
+		return float64(acc)
+	}
+}
+
+// newSHA256Kernel repeatedly hashes a buffer, feeding each digest back into it
+func newSHA256Kernel() cpuKernel {
+	buffer := make([]byte, sha256BufferSize)
+	rand.Read(buffer)
+
+	return func() float64 {
+		digest := sha256.Sum256(buffer)
+		copy(buffer, digest[:])
+		return float64(binary.LittleEndian.Uint64(digest[:8]))
+	}
+}
+
+// newCompressKernel compresses semi-random text, which is compressible but not trivially so
+func newCompressKernel() cpuKernel {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz      \n"
+	input := make([]byte, compressInputSize)
+	for i := range input {
+		input[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+
+	var output bytes.Buffer
+	writer, _ := flate.NewWriter(&output, flate.DefaultCompression)
+	offset := 0
+
+	return func() float64 {
+		// Mutate a few bytes so every batch compresses different data
+		input[offset%len(input)] = alphabet[offset%len(alphabet)]
+		offset += 7919
+
+		output.Reset()
+		writer.Reset(&output)
+		writer.Write(input)
+		writer.Close()
+		return float64(output.Len())
+	}
+}
+
+// newSortKernel sorts freshly shuffled integers, producing unpredictable branches
+func newSortKernel() cpuKernel {
+	values := make([]int, sortBatchSize)
+	random := rand.New(rand.NewSource(rand.Int63()))
+
+	return func() float64 {
+		for i := range values {
+			values[i] = random.Int()
+		}
+		sort.Ints(values)
+		return float64(values[len(values)/2])
+	}
+}
+
+// newCacheKernel performs dependent random reads over a buffer much larger than the CPU caches
+func newCacheKernel() cpuKernel {
+	cacheBufferOnce.Do(func() {
+		cacheBuffer = make([]uint32, cacheBufferSize/4)
+		for i := range cacheBuffer {
+			cacheBuffer[i] = rand.Uint32()
+		}
+	})
+
+	mask := uint32(len(cacheBuffer) - 1)
+	index := rand.Uint32() & mask
+
+	return func() float64 {
+		var sum uint64
+		for i := 0; i < cacheBatchSize; i++ {
+			// The next address depends on the value just loaded, defeating the prefetcher
+			value := cacheBuffer[index]
+			sum += uint64(value)
+			index = (value ^ index*2654435761) & mask
+		}
+		return float64(sum)
+	}
+}
+
+// newMatrixKernel multiplies two dense matrices
+func newMatrixKernel() cpuKernel {
+	a := make([]float64, matrixSize*matrixSize)
+	b := make([]float64, matrixSize*matrixSize)
+	c := make([]float64, matrixSize*matrixSize)
+	for i := range a {
+		a[i] = rand.Float64()
+		b[i] = rand.Float64()
+	}
+
+	return func() float64 {
+		for i := 0; i < matrixSize; i++ {
+			for j := 0; j < matrixSize; j++ {
+				sum := 0.0
+				for k := 0; k < matrixSize; k++ {
+					sum += a[i*matrixSize+k] * b[k*matrixSize+j]
+				}
+				c[i*matrixSize+j] = sum
+			}
+		}
+		// Feed a result back so consecutive batches differ
+		a[0] = c[matrixSize*matrixSize-1] / matrixSize
+		return c[0]
+	}
+}
@@ -0,0 +1,266 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency sweep settings
+const (
+	cacheLineSize          = 64                     // Every access of the chase lands on its own cache line
+	chaseBatch             = 1 << 14                // Accesses between two checks of the clock
+	defaultLatencyMinKB    = 4                      // Smallest size of the default sweep
+	defaultLatencyMaxKB    = 256 * 1024             // Largest size of the default sweep, beyond the caches of most hosts
+	defaultLatencyDuration = 200 * time.Millisecond // Time spent measuring each size
+	cpuCacheDir            = "/sys/devices/system/cpu/cpu0/cache"
+)
+
+// MaxLatencySizes is the number of sizes a single sweep may measure
+const MaxLatencySizes = 64
+
+// Errors returned when a latency sweep cannot run
+var (
+	ErrLatencyBusy   = errors.New("a latency sweep is already in progress")
+	ErrLatencyMemory = errors.New("latency buffer does not fit into memory")
+)
+
+// LatencyOptions describes the buffer sizes of a latency sweep
+type LatencyOptions struct {
+	SizesKB  []int         // Buffer sizes to measure, empty sweeps powers of two from 4 KB to 256 MB
+	Duration time.Duration // Time spent measuring each size, 0 uses the default of 200ms
+}
+
+// CacheLevel is a data cache of the CPU as reported by sysfs
+type CacheLevel struct {
+	Name   string `json:"name"` // L1d, L2 or L3
+	SizeKB int    `json:"size_kb"`
+}
+
+// LatencyPoint is the measured access latency at one buffer size
+type LatencyPoint struct {
+	SizeKB      int     `json:"size_kb"`
+	Level       string  `json:"level"` // Smallest cache the buffer fits into, or "memory"
+	NsPerAccess float64 `json:"ns_per_access"`
+	Accesses    uint64  `json:"accesses"`
+}
+
+// LatencyResult is the outcome of a latency sweep
+type LatencyResult struct {
+	MeasuredAt      time.Time      `json:"measured_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Caches          []CacheLevel   `json:"caches"` // Empty if the cache sizes are not known
+	Points          []LatencyPoint `json:"points"`
+}
+
+// Global latency sweep state
+var (
+	latestLatency  *LatencyResult
+	latencyRunning bool
+	latencyMutex   sync.Mutex
+)
+
+// readCacheLevels reads the data and unified caches of the first CPU from sysfs, smallest first
+func readCacheLevels() []CacheLevel {
+	dirs, _ := filepath.Glob(filepath.Join(cpuCacheDir, "index*"))
+	levels := []CacheLevel{}
+	for _, dir := range dirs {
+		read := func(name string) string {
+			content, _ := os.ReadFile(filepath.Join(dir, name))
+			return strings.TrimSpace(string(content))
+		}
+		if read("type") == "Instruction" {
+			continue
+		}
+
+		// Sizes look like "48K" or "105M"
+		size := read("size")
+		scale := 1
+		if strings.HasSuffix(size, "M") {
+			scale = 1024
+		}
+		sizeKB, err := strconv.Atoi(strings.TrimRight(size, "KM"))
+		if err != nil {
+			continue
+		}
+		name := "L" + read("level")
+		if name == "L1" {
+			name = "L1d"
+		}
+		levels = append(levels, CacheLevel{Name: name, SizeKB: sizeKB * scale})
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].SizeKB < levels[j].SizeKB })
+	return levels
+}
+
+// cacheLevelFor returns the smallest cache a buffer of the given size fits into
+func cacheLevelFor(sizeKB int, caches []CacheLevel) string {
+	for _, cache := range caches {
+		if sizeKB <= cache.SizeKB {
+			return cache.Name
+		}
+	}
+	return "memory"
+}
+
+// newChaseBuffer links one word per cache line of a buffer into a single cycle visiting the lines in random order,
+// so every access depends on the previous one and the hardware prefetcher cannot predict the next
+func newChaseBuffer(size int) []uint64 {
+	const stride = cacheLineSize / 8
+	lines := size / cacheLineSize
+	order := rand.Perm(lines)
+
+	buffer := make([]uint64, lines*stride)
+	for i := 0; i < lines; i++ {
+		buffer[order[i]*stride] = uint64(order[(i+1)%lines] * stride)
+	}
+	return buffer
+}
+
+// chase follows the cycle for the given number of accesses and returns the position it stopped at
+func chase(buffer []uint64, position uint64, accesses int) uint64 {
+	for i := 0; i < accesses; i++ {
+		position = buffer[position]
+	}
+	return position
+}
+
+// measureLatency returns the mean time per access when chasing through a buffer of the given size
+func measureLatency(sizeKB int, duration time.Duration) (float64, uint64) {
+	buffer := newChaseBuffer(sizeKB * 1024)
+
+	// Warm up the caches and TLB with up to one pass over the cycle
+	warmup := len(buffer) / (cacheLineSize / 8)
+	if warmup > 1<<20 {
+		warmup = 1 << 20
+	}
+	position := chase(buffer, 0, warmup)
+
+	var accesses uint64
+	start := time.Now()
+	for time.Since(start) < duration {
+		position = chase(buffer, position, chaseBatch)
+		accesses += chaseBatch
+	}
+	elapsed := time.Since(start)
+
+	// Use the position to prevent optimization
+	if position == uint64(len(buffer)) {
+		fmt.Println("Unexpected chase position")
+	}
+	return float64(elapsed.Nanoseconds()) / float64(accesses), accesses
+}
+
+// defaultLatencySizes returns powers of two from defaultLatencyMinKB to defaultLatencyMaxKB
+func defaultLatencySizes() []int {
+	sizes := []int{}
+	for size := defaultLatencyMinKB; size <= defaultLatencyMaxKB; size *= 2 {
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// MeasureLatency measures the access latency of a pointer chase over buffers of the given sizes.
+// Only one sweep runs at a time; it runs alongside other jobs, which show up as higher latencies.
+// Returns ErrCalibrating while a calibration runs, ErrLatencyBusy while another sweep runs
+// and ErrLatencyMemory if the largest buffer would not fit.
+func MeasureLatency(options LatencyOptions) (LatencyResult, error) {
+	if len(options.SizesKB) == 0 {
+		options.SizesKB = defaultLatencySizes()
+		// The default sweep ends where the memory does
+		if budget := GetCgroupLimits().MemoryBudgetMB; budget > 0 {
+			for len(options.SizesKB) > 1 && int64(options.SizesKB[len(options.SizesKB)-1]/1024) > budget/2 {
+				options.SizesKB = options.SizesKB[:len(options.SizesKB)-1]
+			}
+		}
+	}
+	if options.Duration <= 0 {
+		options.Duration = defaultLatencyDuration
+	}
+	sort.Ints(options.SizesKB)
+
+	largest := options.SizesKB[len(options.SizesKB)-1]
+	if _, err := checkCgroupHeadroom(int64(largest)*1024, 0); err != nil {
+		return LatencyResult{}, fmt.Errorf("%w: %v", ErrLatencyMemory, err)
+	}
+	if budget := GetCgroupLimits().MemoryBudgetMB; budget > 0 && int64(largest/1024) > budget/2 {
+		return LatencyResult{}, fmt.Errorf("%w: %d KB exceeds half of the %d MB available", ErrLatencyMemory, largest, budget)
+	}
+
+	// Like a job, a sweep is refused while a calibration runs
+	if err := beginJobStart(); err != nil {
+		return LatencyResult{}, err
+	}
+	latencyMutex.Lock()
+	if latencyRunning {
+		latencyMutex.Unlock()
+		endJobStart()
+		return LatencyResult{}, ErrLatencyBusy
+	}
+	latencyRunning = true
+	latencyMutex.Unlock()
+	endJobStart()
+
+	defer func() {
+		latencyMutex.Lock()
+		latencyRunning = false
+		latencyMutex.Unlock()
+	}()
+
+	result := LatencyResult{
+		MeasuredAt: time.Now(),
+		Caches:     readCacheLevels(),
+		Points:     make([]LatencyPoint, 0, len(options.SizesKB)),
+	}
+	fmt.Printf("Measuring memory latency at %d sizes for %s each...\n", len(options.SizesKB), options.Duration)
+	for _, size := range options.SizesKB {
+		ns, accesses := measureLatency(size, options.Duration)
+		result.Points = append(result.Points, LatencyPoint{
+			SizeKB:      size,
+			Level:       cacheLevelFor(size, result.Caches),
+			NsPerAccess: ns,
+			Accesses:    accesses,
+		})
+	}
+	result.DurationSeconds = time.Since(result.MeasuredAt).Seconds()
+
+	first, last := result.Points[0], result.Points[len(result.Points)-1]
+	recordEvent("memory.latency", map[string]interface{}{
+		"sizes":   len(result.Points),
+		"min_kb":  first.SizeKB,
+		"max_kb":  last.SizeKB,
+		"seconds": result.DurationSeconds,
+	}, "Measured memory latency at %d sizes: %.1f ns at %d KB (%s) to %.1f ns at %d KB (%s)",
+		len(result.Points), first.NsPerAccess, first.SizeKB, first.Level, last.NsPerAccess, last.SizeKB, last.Level)
+
+	latencyMutex.Lock()
+	latestLatency = &result
+	latencyMutex.Unlock()
+
+	return result, nil
+}
+
+// GetLatency returns the result of the most recent latency sweep, or false if none ran yet
+func GetLatency() (LatencyResult, bool) {
+	latencyMutex.Lock()
+	defer latencyMutex.Unlock()
+
+	if latestLatency == nil {
+		return LatencyResult{}, false
+	}
+	return *latestLatency, true
+}
+
+// IsMeasuringLatency returns whether a latency sweep is currently in progress
+func IsMeasuringLatency() bool {
+	latencyMutex.Lock()
+	defer latencyMutex.Unlock()
+	return latencyRunning
+}
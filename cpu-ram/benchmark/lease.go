@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Lease keeps a task alive only as long as the client keeps renewing it (dead man's switch)
+type Lease struct {
+	ID         string        `json:"id"`
+	Kind       string        `json:"kind"`   // Kind of the job the lease belongs to, e.g. "cpu" or "memory"
+	JobID      string        `json:"job_id"` // Job stopped when the lease expires
+	TTL        time.Duration `json:"-"`
+	TTLSeconds float64       `json:"ttl_seconds"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	Renewals   int           `json:"renewals"`
+
+	timer *time.Timer
+}
+
+// Global lease registry
+var (
+	leases      map[string]*Lease
+	leasesMutex sync.Mutex
+)
+
+// init initializes the package-level variables
+func init() {
+	leases = make(map[string]*Lease)
+}
+
+// newLeaseID returns a random identifier for a lease
+func newLeaseID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// newLease registers a lease for a job that calls onExpire unless it is renewed within ttl
+func newLease(kind, jobID string, ttl time.Duration, onExpire func()) Lease {
+	lease := &Lease{
+		ID:         newLeaseID(),
+		Kind:       kind,
+		JobID:      jobID,
+		TTL:        ttl,
+		TTLSeconds: ttl.Seconds(),
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+
+	leasesMutex.Lock()
+	defer leasesMutex.Unlock()
+
+	lease.timer = time.AfterFunc(ttl, func() {
+		leasesMutex.Lock()
+		_, active := leases[lease.ID]
+		delete(leases, lease.ID)
+		leasesMutex.Unlock()
+
+		// The lease may have been cancelled while the timer was firing
+		if active {
+			recordEvent("lease.expire", map[string]interface{}{"lease": lease.ID, "job": jobID},
+				"Lease %s for job %s expired without renewal", lease.ID, jobID)
+			onExpire()
+		}
+	})
+	leases[lease.ID] = lease
+
+	return *lease
+}
+
+// cancelLease removes a lease without triggering its expiry action
+func cancelLease(id string) {
+	if id == "" {
+		return
+	}
+
+	leasesMutex.Lock()
+	defer leasesMutex.Unlock()
+
+	if lease, ok := leases[id]; ok {
+		lease.timer.Stop()
+		delete(leases, id)
+	}
+}
+
+// RenewLease extends the lease by its TTL from now
+// Returns the renewed lease, and false if it does not exist or has already expired
+func RenewLease(id string) (Lease, bool) {
+	leasesMutex.Lock()
+	defer leasesMutex.Unlock()
+
+	lease, ok := leases[id]
+	if !ok || !lease.timer.Stop() {
+		return Lease{}, false
+	}
+
+	lease.timer.Reset(lease.TTL)
+	lease.ExpiresAt = time.Now().Add(lease.TTL)
+	lease.Renewals++
+
+	return *lease, true
+}
+
+// GetLease returns the lease with the given ID
+func GetLease(id string) (Lease, bool) {
+	leasesMutex.Lock()
+	defer leasesMutex.Unlock()
+
+	lease, ok := leases[id]
+	if !ok {
+		return Lease{}, false
+	}
+	return *lease, true
+}
+
+// GetLeases returns all active leases ordered by expiry
+func GetLeases() []Lease {
+	leasesMutex.Lock()
+	defer leasesMutex.Unlock()
+
+	result := make([]Lease, 0, len(leases))
+	for _, lease := range leases {
+		result = append(result, *lease)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ExpiresAt.Before(result[j].ExpiresAt) })
+	return result
+}
@@ -1,251 +1,702 @@
 package benchmark
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Global variables to control the memory benchmark task
-var (
-	memoryTaskRunning bool
-	memoryTaskChan    chan bool
-	memoryTaskWg      sync.WaitGroup
-	memoryTaskMutex   sync.Mutex
+// memoryBlock is one allocated block, owned by the job that allocated it
+type memoryBlock struct {
+	data     []byte
+	owner    string // ID of the job that allocated the block
+	id       uint64 // Unique block number, used to derive the fill pattern
+	modified uint32 // Set atomically once a writer changed the block after it was filled
+	mapped   bool   // Data is an mmap region outside the Go heap
+
+	// Guards data against being released while walkers or verification read it,
+	// and is held exclusively while a write walk modifies it. Data is nil once the block was released.
+	mutex sync.RWMutex
+}
 
-	// Memory storage
-	memoryBlocks      [][]byte
+// Memory storage shared by all memory jobs and trace replays.
+// Blocks outlive the job that allocated them until they are freed.
+var (
+	memoryBlocks      []*memoryBlock
+	memoryOwned       map[string]int64 // Allocated bytes per owning job, jobs without blocks have no entry
+	memoryTotal       int64            // Allocated bytes across all jobs
+	memoryMapped      int64            // Allocated bytes mapped outside the Go heap
 	memoryBlocksMutex sync.Mutex
-	maxMemoryMB       int // Maximum memory to allocate in MB
+
+	memoryBlockCounter uint64 // Number of blocks created so far, updated atomically
 )
 
+// MemoryTaskOptions describes how a memory benchmark task should allocate memory
+type MemoryTaskOptions struct {
+	LimitMB       int           // Maximum memory to allocate, 0 uses the default of 1024 MB
+	Quota         float64       // Limit as a share of the cgroup memory limit (or host memory) in (0, 1], overrides LimitMB when set
+	BlockSize     int           // Size of each allocated block in bytes, 0 uses the default of 10 MB
+	Seed          int64         // Seed of the fill pattern, 0 picks a random seed
+	Backend       string        // Allocator of the blocks, BackendHeap (default) or BackendMmap
+	HugePages     string        // Huge page advice for mmap blocks, HugePagesOn, HugePagesOff or HugePagesDefault
+	Lock          bool          // mlock mmap blocks so they cannot be swapped out
+	CgroupGuard   float64       // Stop growing once the cgroup would use this share of memory.max in percent, 0 only keeps clear of the limit
+	RateMBps      float64       // Growth rate in MB per second, 0 uses the default of 20 MB/s
+	Immediate     bool          // Allocate the whole limit at once, ignoring RateMBps
+	HotFraction   float64       // Share of the allocated blocks kept active by a walker, 0 leaves them untouched
+	TouchRateMBps float64       // Pages of the hot set touched per second in MB, 0 uses the default of 100 MB/s
+	TouchWrite    bool          // Modify the touched pages instead of only reading them
+	Duration      time.Duration // Stop the task automatically after this long, 0 runs until stopped
+	FreeAfter     bool          // Release the memory when the duration elapses instead of keeping it
+	LeaseTTL      time.Duration // Stop the task and free its memory unless the lease is renewed within this TTL
+}
+
 // Memory allocation sizes
 const (
-	blockSize          = 10 * 1024 * 1024 // 10MB per block
-	allocationDelay    = 500 * time.Millisecond
+	bytesPerMB         = 1024 * 1024
+	defaultBlockSize   = 10 * bytesPerMB // 10MB per block
+	minBlockSize       = 4096            // One page, smaller blocks would share pages
+	defaultRateMBps    = 20.0            // One default block every 500ms
+	defaultTouchMBps   = 100.0           // Default rate at which the hot set is touched
+	allocationTick     = 50 * time.Millisecond
 	statusInterval     = 5 * time.Second
 	defaultMaxMemoryMB = 1024 // Default max memory is 1GB (1024MB)
 )
 
 // init initializes the package-level variables
 func init() {
-	memoryTaskRunning = false
-	memoryTaskChan = make(chan bool, 1) // Use buffered channel to prevent blocking
-	maxMemoryMB = defaultMaxMemoryMB
+	memoryOwned = make(map[string]int64)
+	memoryManifest = make(map[string]map[uint64]blockRecord)
 }
 
-// SetMaxMemoryMB sets the maximum amount of memory in MB that the benchmark can allocate
-// Returns the actual limit that was set
-func SetMaxMemoryMB(mbLimit int) int {
-	memoryTaskMutex.Lock()
-	defer memoryTaskMutex.Unlock()
+// collectMemory forces garbage collection so released blocks are returned to the system
+func collectMemory() {
+	runtime.GC()
 
-	if mbLimit <= 0 {
-		maxMemoryMB = defaultMaxMemoryMB
+	// Wait a moment and force another GC for good measure
+	time.Sleep(500 * time.Millisecond)
+	runtime.GC()
+}
+
+// freeMemory releases the blocks of the given job, or all blocks if owner is empty,
+// and forces garbage collection. Returns the released amount in MB.
+func freeMemory(owner string) int {
+	memoryBlocksMutex.Lock()
+	kept := memoryBlocks[:0]
+	var dropped []*memoryBlock
+	var released int64
+	for _, block := range memoryBlocks {
+		if owner == "" || block.owner == owner {
+			released += forgetMemoryBlock(block)
+			unrecordBlock(block)
+			dropped = append(dropped, block)
+			continue
+		}
+		kept = append(kept, block)
+	}
+	// Clear the dropped references so the garbage collector can reclaim them
+	for i := len(kept); i < len(memoryBlocks); i++ {
+		memoryBlocks[i] = nil
+	}
+	memoryBlocks = kept
+	memoryBlocksMutex.Unlock()
+	releaseBlocks(dropped)
+
+	releasedMB := int(released / bytesPerMB)
+	fmt.Printf("Cleaning up %d MB of allocated memory...\n", releasedMB)
+	collectMemory()
+	fmt.Println("Memory cleanup complete - memory should now be released to the system")
+
+	data := map[string]interface{}{"released_mb": releasedMB}
+	if owner != "" {
+		data["job"] = owner
+		recordEvent("memory.free", data, "Released %d MB of memory of job %s", releasedMB, owner)
 	} else {
-		maxMemoryMB = mbLimit
+		recordEvent("memory.free", data, "Released %d MB of memory", releasedMB)
+	}
+	return releasedMB
+}
+
+// newMemoryBlock creates a new memory block and fills it with a pattern derived from the seed,
+// which ensures it's actually allocated and lets its integrity be verified later.
+// Returns the block and the checksum of its pattern, which goes into the manifest.
+func newMemoryBlock(owner string, size int, seed uint64, alloc allocator) (*memoryBlock, uint32, error) {
+	data, mapped, err := alloc.allocate(size)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return maxMemoryMB
+	block := &memoryBlock{
+		data:   data,
+		owner:  owner,
+		id:     atomic.AddUint64(&memoryBlockCounter, 1),
+		mapped: mapped,
+	}
+	fillPattern(block.data, patternSeed(seed, block.id))
+	return block, blockChecksum(block.data), nil
 }
 
-// GetMaxMemoryMB returns the current maximum memory limit in MB
-func GetMaxMemoryMB() int {
-	memoryTaskMutex.Lock()
-	defer memoryTaskMutex.Unlock()
-	return maxMemoryMB
+// addMemoryBlock adds a block to the pool and its checksum to the manifest.
+// Returns the bytes now allocated by the block's owner.
+func addMemoryBlock(block *memoryBlock, checksum uint32) int64 {
+	memoryBlocksMutex.Lock()
+	defer memoryBlocksMutex.Unlock()
+
+	memoryBlocks = append(memoryBlocks, block)
+	recordBlock(block, checksum)
+	memoryOwned[block.owner] += int64(len(block.data))
+	memoryTotal += int64(len(block.data))
+	if block.mapped {
+		memoryMapped += int64(len(block.data))
+	}
+	return memoryOwned[block.owner]
+}
+
+// forgetMemoryBlock removes a block leaving the pool from the totals,
+// the caller holds memoryBlocksMutex. Returns the size of the block.
+func forgetMemoryBlock(block *memoryBlock) int64 {
+	size := int64(len(block.data))
+	memoryOwned[block.owner] -= size
+	if memoryOwned[block.owner] <= 0 {
+		// The job's last block is gone, drop its entry so finished jobs do not pile up
+		delete(memoryOwned, block.owner)
+	}
+	memoryTotal -= size
+	if block.mapped {
+		memoryMapped -= size
+	}
+	return size
 }
 
-// freeMemory explicitly releases memory by clearing the memory blocks
-// and forcing garbage collection
-func freeMemory() {
+// ownedMemoryBytes returns the memory allocated by the given job in bytes
+func ownedMemoryBytes(owner string) int64 {
 	memoryBlocksMutex.Lock()
-	// Get current memory allocation for reporting
-	allocatedMB := 0
-	if memoryBlocks != nil {
-		allocatedMB = len(memoryBlocks) * blockSize / (1024 * 1024)
+	defer memoryBlocksMutex.Unlock()
+	return memoryOwned[owner]
+}
+
+// ownedMemoryMB returns the memory allocated by the given job in MB
+func ownedMemoryMB(owner string) int {
+	return int(ownedMemoryBytes(owner) / bytesPerMB)
+}
+
+// dropMemoryBlocks removes the most recently allocated blocks of the given job, or of any job
+// if owner is empty, until the allocation is at most target bytes. Returns the bytes dropped.
+func dropMemoryBlocks(owner string, target int64) int64 {
+	memoryBlocksMutex.Lock()
+
+	current := func() int64 {
+		if owner == "" {
+			return memoryTotal
+		}
+		return memoryOwned[owner]
 	}
 
-	// Explicitly set to nil to release references
-	memoryBlocks = nil
+	var blocks []*memoryBlock
+	var dropped int64
+	for i := len(memoryBlocks) - 1; i >= 0 && current() > target; i-- {
+		block := memoryBlocks[i]
+		if owner != "" && block.owner != owner {
+			continue
+		}
+		copy(memoryBlocks[i:], memoryBlocks[i+1:])
+		memoryBlocks[len(memoryBlocks)-1] = nil
+		memoryBlocks = memoryBlocks[:len(memoryBlocks)-1]
+
+		dropped += forgetMemoryBlock(block)
+		unrecordBlock(block)
+		blocks = append(blocks, block)
+	}
 	memoryBlocksMutex.Unlock()
 
-	// Force garbage collection
-	fmt.Printf("Cleaning up %d MB of allocated memory...\n", allocatedMB)
-	runtime.GC()
+	// Mapped blocks are unmapped outside the lock, heap blocks wait for the garbage collector
+	releaseBlocks(blocks)
+	return dropped
+}
 
-	// Wait a moment and force another GC for good measure
-	time.Sleep(500 * time.Millisecond)
-	runtime.GC()
-	fmt.Println("Memory cleanup complete - memory should now be released to the system")
+// resizeMemory allocates or drops default-sized blocks of the given job until its allocation
+// matches targetMB (rounded down to whole blocks), never beyond the available memory and
+// stopping short of the cgroup memory limit. A signal on stop is checked between blocks.
+// Returns the resulting allocation in MB and whether growing was interrupted by a stop.
+func resizeMemory(owner string, targetMB int, seed uint64, stop <-chan bool) (int, bool) {
+	if budget := GetCgroupLimits().MemoryBudgetMB; budget > 0 && int64(targetMB) > budget {
+		targetMB = int(budget)
+	}
+	target := int64(targetMB) * bytesPerMB
+	target -= target % defaultBlockSize
+	if target < 0 {
+		target = 0
+	}
+
+	owned := ownedMemoryBytes(owner)
+	for owned < target {
+		select {
+		case <-stop:
+			return ownedMemoryMB(owner), true
+		default:
+		}
+
+		if _, err := checkCgroupHeadroom(defaultBlockSize, 0); err != nil {
+			break
+		}
+		block, checksum, err := newMemoryBlock(owner, defaultBlockSize, seed, heapAllocator)
+		if err != nil {
+			break
+		}
+		owned = addMemoryBlock(block, checksum)
+	}
+	if owned > target && dropMemoryBlocks(owner, target) > 0 {
+		runtime.GC()
+	}
+
+	return ownedMemoryMB(owner), false
 }
 
-// startMemoryTask continuously allocates memory until signaled to stop or reaching the limit
-func startMemoryTask() {
-	defer memoryTaskWg.Done()
+// memoryTask allocates blocks for one memory job at a fixed rate until it reaches its limit
+type memoryTask struct {
+	owner     string    // ID of the job
+	seed      uint64    // Seed of the fill pattern
+	alloc     allocator // Backend the blocks are allocated from
+	limit     int64     // Bytes to allocate in total
+	blockSize int
+	rateMBps  float64
+	immediate bool
+	allocated int64 // Bytes allocated so far, released memory is not allocated again
+
+	cgroupGuard float64 // Share of the cgroup memory.max in percent the usage may grow to, 0 only keeps clear of the limit
+	headroom    int64   // Memory the cgroup can take before its usage has to be read again
+
+	walker *pageWalker // Keeps the hot part of the allocation active, nil if disabled
+
+	job      *Job // Set before the task starts
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex        sync.Mutex
+	running      bool
+	limitReached bool   // No more memory is allocated, because the limit was reached or allocation failed
+	limitError   string // Why allocation stopped short of the limit, empty if the limit was reached
+}
 
-	// Get the memory limit at task start time
-	memoryTaskMutex.Lock()
-	memoryLimit := maxMemoryMB
-	memoryTaskMutex.Unlock()
+// errAllocationStopped is returned by allocateUpTo when the task was signaled to stop while allocating
+var errAllocationStopped = errors.New("stop requested while allocating")
 
-	fmt.Printf("Memory benchmark task started - will allocate up to %d MB\n", memoryLimit)
+// allocateUpTo allocates blocks until the job has allocated target bytes, the last block may be smaller.
+// Blocks are only added while they fit entirely below the target, so the rate is never exceeded.
+// Memory released in the meantime counts as allocated, so a shrinking job is not refilled.
+// A stop signal is checked between blocks, so a large immediate allocation can be stopped or expire.
+// Returns the bytes allocated by the job so far, and the error if a block could not be allocated.
+func (t *memoryTask) allocateUpTo(target int64) (int64, error) {
+	for t.allocated < target {
+		select {
+		case <-t.stopChan:
+			return t.allocated, errAllocationStopped
+		default:
+		}
 
-	// Initialize the memory blocks slice if it doesn't exist
-	memoryBlocksMutex.Lock()
-	if memoryBlocks == nil {
-		// Pre-allocate capacity for the slice
-		memoryBlocks = make([][]byte, 0, memoryLimit/(blockSize/(1024*1024)))
+		size := int64(t.blockSize)
+		if remaining := t.limit - t.allocated; remaining < size {
+			size = remaining
+		}
+		if t.allocated+size > target {
+			break
+		}
+
+		// Stop short of the cgroup limit instead of being OOM-killed, the Go heap cannot
+		// report a failed allocation. The usage is read again after every few blocks.
+		if t.headroom < size {
+			headroom, err := checkCgroupHeadroom(size, t.cgroupGuard)
+			if err != nil {
+				return t.allocated, err
+			}
+			t.headroom = headroom
+		}
+
+		block, checksum, err := newMemoryBlock(t.owner, int(size), t.seed, t.alloc)
+		if err != nil {
+			return t.allocated, err
+		}
+		addMemoryBlock(block, checksum)
+		t.allocated += size
+		t.headroom -= size
+	}
+	return t.allocated, nil
+}
+
+// limitPct returns the given allocation as a percentage of the task's limit, 0 without a limit
+func (t *memoryTask) limitPct(allocated int64) int64 {
+	if t.limit <= 0 {
+		return 0
 	}
-	// Calculate current allocation
-	currentAllocation := 0
-	if len(memoryBlocks) > 0 {
-		currentAllocation = len(memoryBlocks) * blockSize / (1024 * 1024)
-		fmt.Printf("Reusing existing memory allocation of %d MB\n", currentAllocation)
+	return allocated * 100 / t.limit
+}
+
+// reachLimit marks the task and its job as no longer growing, with the error if it stopped short of its limit
+func (t *memoryTask) reachLimit(err error) {
+	t.mutex.Lock()
+	t.limitReached = true
+	if err != nil {
+		t.limitError = err.Error()
+	}
+	t.mutex.Unlock()
+
+	t.job.reachLimit()
+}
+
+// limitState returns whether the task stopped growing and the error that stopped it early
+func (t *memoryTask) limitState() (bool, string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.limitReached, t.limitError
+}
+
+// allocationFailed reports a block that could not be allocated; the task keeps what it has
+func (t *memoryTask) allocationFailed(err error) {
+	t.reachLimit(err)
+	allocatedMB := ownedMemoryMB(t.owner)
+	fmt.Printf("\nMemory benchmark task %s could not allocate more memory after %d MB: %v\n", t.owner, allocatedMB, err)
+	recordEvent("memory.alloc_failed", map[string]interface{}{
+		"job":          t.owner,
+		"allocated_mb": allocatedMB,
+		"error":        err.Error(),
+	}, "Memory job %s stopped growing at %d MB: %v", t.owner, allocatedMB, err)
+}
+
+// run allocates memory until signaled to stop, then keeps the task idle at its limit
+func (t *memoryTask) run() {
+	defer t.wg.Done()
+
+	limitMB := int(t.limit / bytesPerMB)
+	if t.immediate {
+		fmt.Printf("Memory benchmark task %s started - allocating %d MB immediately in %d KB %s blocks\n",
+			t.owner, limitMB, t.blockSize/1024, t.alloc)
+	} else {
+		fmt.Printf("Memory benchmark task %s started - will allocate up to %d MB at %.1f MB/s in %d KB %s blocks\n",
+			t.owner, limitMB, t.rateMBps, t.blockSize/1024, t.alloc)
 	}
-	memoryBlocksMutex.Unlock()
 
 	// Create a ticker for memory allocation and status updates
-	allocTicker := time.NewTicker(allocationDelay)
+	allocTicker := time.NewTicker(allocationTick)
 	statusTicker := time.NewTicker(statusInterval)
 	defer allocTicker.Stop()
 	defer statusTicker.Stop()
 
-	allocatedMB := currentAllocation
+	// Stop the task but do NOT free memory
+	finish := func() {
+		fmt.Printf("Memory benchmark task %s stopped after allocating %d MB\n", t.owner, ownedMemoryMB(t.owner))
+		fmt.Println("Memory is still allocated. Use /memory/free endpoint to release it.")
+	}
+
+	startTime := time.Now()
+	var allocated int64
+	var err error
+	limitReached := false
+	if t.immediate {
+		allocated, err = t.allocateUpTo(t.limit)
+		if err == errAllocationStopped {
+			finish()
+			return
+		}
+		if err != nil {
+			t.allocationFailed(err)
+			allocTicker.Stop()
+			limitReached = true
+		}
+	}
 
 	// Main control loop
 	for {
+		if allocated >= t.limit && !limitReached {
+			fmt.Printf("\nReached memory allocation limit of %d MB after %s. Stopping further allocations.\n",
+				limitMB, time.Since(startTime).Round(time.Millisecond))
+			// Keep the task running, but stop allocating more memory
+			allocTicker.Stop()
+			limitReached = true
+			t.reachLimit(nil)
+		}
+
 		select {
-		case <-memoryTaskChan:
-			// Stop the task but do NOT free memory
-			fmt.Printf("Memory benchmark task stopped after allocating %d MB\n", allocatedMB)
-			fmt.Println("Memory is still allocated. Use /memory/free endpoint to release it.")
+		case <-t.stopChan:
+			finish()
 			return
 
 		case <-allocTicker.C:
-			// Check if we've reached the memory limit
-			if allocatedMB >= memoryLimit {
-				fmt.Printf("\nReached memory allocation limit of %d MB. Stopping further allocations.\n", memoryLimit)
-				// Keep the task running, but stop allocating more memory
+			// Allocate what the growth rate allows for the elapsed time
+			target := int64(t.rateMBps * time.Since(startTime).Seconds() * bytesPerMB)
+			if target > t.limit {
+				target = t.limit
+			}
+			previous := allocated
+			allocated, err = t.allocateUpTo(target)
+			if err == errAllocationStopped {
+				finish()
+				return
+			}
+			if err != nil {
+				// Keep the task running with what it has, but stop allocating more memory
+				t.allocationFailed(err)
 				allocTicker.Stop()
-				continue
+				limitReached = true
 			}
 
-			// Allocate a new memory block
-			memoryBlocksMutex.Lock()
-			// Create a new memory block and fill it with data to ensure it's actually allocated
-			newBlock := make([]byte, blockSize)
-			for i := 0; i < len(newBlock); i += 1024 { // Fill every 1KB to ensure allocation
-				newBlock[i] = byte(i % 256)
+			if allocated != previous {
+				fmt.Printf("\rAllocated %d MB of memory (%d%% of limit)...",
+					allocated/bytesPerMB, t.limitPct(allocated))
 			}
-			memoryBlocks = append(memoryBlocks, newBlock)
-			currentBlocks := len(memoryBlocks)
-			allocatedMB = currentBlocks * blockSize / (1024 * 1024)
-			memoryBlocksMutex.Unlock()
-
-			fmt.Printf("\rAllocated %d MB of memory (%d%% of limit)...",
-				allocatedMB, allocatedMB*100/memoryLimit)
 
 		case <-statusTicker.C:
-			// Get current memory usage
-			memoryBlocksMutex.Lock()
-			currentBlocks := len(memoryBlocks)
-			allocatedMB = currentBlocks * blockSize / (1024 * 1024)
-			memoryBlocksMutex.Unlock()
+			owned := ownedMemoryBytes(t.owner)
+			fmt.Printf("\nMemory benchmark %s running - using approximately %d MB (%d%% of %d MB limit)\n",
+				t.owner, owned/bytesPerMB, t.limitPct(owned), limitMB)
+		}
+	}
+}
+
+// stop signals the memory task to stop and waits for it, without freeing memory
+func (t *memoryTask) stop() {
+	t.mutex.Lock()
+
+	if !t.running {
+		t.mutex.Unlock()
+		return
+	}
+
+	if t.walker != nil {
+		t.walker.stop()
+	}
+
+	// Signal the task to stop
+	select {
+	case t.stopChan <- true:
+		fmt.Println("Stop signal sent to memory task")
+	default:
+		fmt.Println("Warning: Channel was full, but proceeding with shutdown")
+	}
+	t.running = false
+
+	// Unlock before waiting to avoid deadlock
+	t.mutex.Unlock()
+
+	fmt.Println("Waiting for memory task to complete shutdown...")
+	t.wg.Wait()
+	fmt.Println("Memory task shutdown complete - memory is still allocated")
+}
+
+// summary describes the memory task in one line
+func (t *memoryTask) summary() string {
+	text := fmt.Sprintf("%d MB of %d MB limit allocated", ownedMemoryMB(t.owner), t.limit/bytesPerMB)
+	if t.immediate {
+		text += fmt.Sprintf(" immediately in %d KB blocks", t.blockSize/1024)
+	} else {
+		text += fmt.Sprintf(" at %.1f MB/s in %d KB blocks", t.rateMBps, t.blockSize/1024)
+	}
+	if t.alloc.backend == BackendMmap {
+		text += fmt.Sprintf(" (%s)", t.alloc)
+	}
+	if _, limitError := t.limitState(); limitError != "" {
+		text += " - stopped growing: " + limitError
+	}
 
-			fmt.Printf("\nMemory benchmark running - using approximately %d MB (%d%% of %d MB limit)\n",
-				allocatedMB, allocatedMB*100/memoryLimit, memoryLimit)
+	if t.walker != nil {
+		mode := "reading"
+		if t.walker.write {
+			mode = "writing"
 		}
+		text += fmt.Sprintf(" - hot set of %.0f MB (%.0f%%) kept active %s %.1f MB/s (measured %.1f MB/s)",
+			t.walker.walkedMB(), t.walker.fraction*100, mode, t.walker.rateMBps, t.walker.measuredRate())
 	}
+	return text
 }
 
-// StartMemoryTask starts the memory-intensive benchmark task
-// Returns true if task was started, false if it was already running
-func StartMemoryTask() bool {
+// details describes the live state of the memory task for job views
+func (t *memoryTask) details() map[string]interface{} {
+	details := map[string]interface{}{
+		"limit_mb":      t.limit / bytesPerMB,
+		"block_size_kb": t.blockSize / 1024,
+		"rate_mbps":     t.rateMBps,
+		"immediate":     t.immediate,
+		"backend":       t.alloc.backend,
+		"allocated_mb":  ownedMemoryMB(t.owner),
+	}
+	limitReached, limitError := t.limitState()
+	details["limit_reached"] = limitReached
+	if limitError != "" {
+		details["limit_error"] = limitError
+	}
+	if t.cgroupGuard > 0 {
+		details["cgroup_guard_pct"] = t.cgroupGuard
+	}
+	if t.alloc.backend == BackendMmap {
+		details["huge_pages"] = t.alloc.hugePages
+		details["locked"] = t.alloc.lock
+	}
+	if t.walker != nil {
+		details["hot_set"] = t.walker.details()
+	}
+	return details
+}
+
+// StartMemoryTask starts a memory job with the default limit
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs
+func StartMemoryTask() (string, error) {
 	return StartMemoryTaskWithLimit(defaultMaxMemoryMB)
 }
 
-// StartMemoryTaskWithLimit starts the memory-intensive benchmark task with a specific MB limit
+// StartMemoryTaskWithLimit starts a memory job with a specific MB limit
 // If limit is <= 0, the default limit (1024 MB) is used
-// Returns true if task was started, false if it was already running
-func StartMemoryTaskWithLimit(mbLimit int) bool {
-	memoryTaskMutex.Lock()
-	defer memoryTaskMutex.Unlock()
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs
+func StartMemoryTaskWithLimit(mbLimit int) (string, error) {
+	return StartMemoryTaskWithOptions(MemoryTaskOptions{LimitMB: mbLimit})
+}
 
-	if memoryTaskRunning {
-		return false
+// StartMemoryTaskWithOptions starts a memory job with the given options.
+// Any number of memory jobs can run side by side, each allocating up to its own limit.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartMemoryTaskWithOptions(options MemoryTaskOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
 	}
+	defer endJobStart()
 
-	// Set the memory limit
-	if mbLimit > 0 {
-		maxMemoryMB = mbLimit
-	} else {
-		maxMemoryMB = defaultMaxMemoryMB
+	limitMB := options.LimitMB
+	if options.Quota > 0 {
+		limitMB = int(options.Quota * float64(GetCgroupLimits().MemoryBudgetMB))
+	}
+	if limitMB <= 0 {
+		limitMB = defaultMaxMemoryMB
 	}
 
-	// Create a new channel for this task
-	memoryTaskChan = make(chan bool, 1)
-
-	// Start the memory task
-	memoryTaskRunning = true
-	memoryTaskWg.Add(1)
-	go startMemoryTask()
+	blockSize := options.BlockSize
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	} else if blockSize < minBlockSize {
+		blockSize = minBlockSize
+	}
+	rate := options.RateMBps
+	if rate <= 0 {
+		rate = defaultRateMBps
+	}
 
-	return true
-}
+	seed := options.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
 
-// StopMemoryTask stops the memory-intensive benchmark task without freeing memory
-// Returns true if task was stopped, false if it wasn't running
-func StopMemoryTask() bool {
-	memoryTaskMutex.Lock()
+	alloc := heapAllocator
+	if options.Backend == BackendMmap {
+		alloc = allocator{backend: BackendMmap, hugePages: options.HugePages, lock: options.Lock}
+	}
 
-	if !memoryTaskRunning {
-		memoryTaskMutex.Unlock()
-		return false
+	// Blocks are tagged with the job ID, so it must be known before allocating
+	id := newJobID(JobKindMemory)
+	task := &memoryTask{
+		owner:       id,
+		seed:        uint64(seed),
+		alloc:       alloc,
+		cgroupGuard: options.CgroupGuard,
+		limit:       int64(limitMB) * bytesPerMB,
+		blockSize:   blockSize,
+		rateMBps:    rate,
+		immediate:   options.Immediate,
+		stopChan:    make(chan bool, 1),
+		running:     true,
 	}
 
-	// Signal the task to stop
-	select {
-	case memoryTaskChan <- true:
-		fmt.Println("Stop signal sent to memory task")
-	default:
-		fmt.Println("Warning: Channel was full, but proceeding with shutdown")
+	// The walker follows the allocation as it grows, touching the oldest blocks
+	if options.HotFraction > 0 {
+		touchRate := options.TouchRateMBps
+		if touchRate <= 0 {
+			touchRate = defaultTouchMBps
+		}
+		task.walker = newPageWalker(id, math.Min(options.HotFraction, 1), touchRate, options.TouchWrite, false)
 	}
 
-	memoryTaskRunning = false
+	job := registerJobWithID(id, JobKindMemory, map[string]interface{}{
+		"limit_mb":      limitMB,
+		"quota":         options.Quota,
+		"block_size_kb": blockSize / 1024,
+		"seed":          seed,
+		"backend":       alloc.backend,
+		"huge_pages":    alloc.hugePages,
+		"locked":        alloc.lock,
+		"cgroup_guard":  options.CgroupGuard,
+		"rate_mbps":     rate,
+		"immediate":     options.Immediate,
+		"hot_fraction":  options.HotFraction,
+		"touch_rate":    options.TouchRateMBps,
+		"touch_write":   options.TouchWrite,
+		"duration":      options.Duration.String(),
+		"free_after":    options.FreeAfter,
+		"lease_ttl":     options.LeaseTTL.String(),
+	}, task)
+
+	task.job = job
+	task.wg.Add(1)
+	go task.run()
+	if task.walker != nil {
+		task.walker.start()
+	}
 
-	// Unlock before waiting to avoid deadlock
-	memoryTaskMutex.Unlock()
+	// A lapsed lease always frees the memory, an elapsed duration only if requested
+	freeAfter := options.FreeAfter
+	job.afterExpire = func(lease bool) {
+		if lease || freeAfter {
+			freeMemory(job.id)
+		}
+	}
+	job.armTimers(options.Duration, options.LeaseTTL)
 
-	fmt.Println("Waiting for memory task to complete shutdown...")
-	memoryTaskWg.Wait()
-	fmt.Println("Memory task shutdown complete - memory is still allocated")
+	return job.id, nil
+}
 
-	return true
+// StopMemoryTask stops all running memory jobs without freeing memory
+// Returns true if any job was stopped, false if none was running
+func StopMemoryTask() bool {
+	return StopJobsOfKind(JobKindMemory) > 0
 }
 
-// IsMemoryTaskRunning returns the current state of the memory benchmark task
+// IsMemoryTaskRunning returns whether any memory job is running
 func IsMemoryTaskRunning() bool {
-	memoryTaskMutex.Lock()
-	defer memoryTaskMutex.Unlock()
-	return memoryTaskRunning
+	return CountRunningJobs(JobKindMemory) > 0
 }
 
-// GetAllocatedMemoryMB returns the current amount of memory allocated by the benchmark in MB
+// GetJobMemoryMB returns the memory currently held by a job in MB
+func GetJobMemoryMB(jobID string) int {
+	return ownedMemoryMB(jobID)
+}
+
+// GetAllocatedMemoryMB returns the current amount of memory allocated by all jobs in MB
 func GetAllocatedMemoryMB() int {
 	memoryBlocksMutex.Lock()
 	defer memoryBlocksMutex.Unlock()
+	return int(memoryTotal / bytesPerMB)
+}
 
-	if memoryBlocks == nil {
-		return 0
-	}
+// GetAllocatedMemoryBytes returns the current amount of memory allocated by all jobs in bytes
+func GetAllocatedMemoryBytes() int64 {
+	return allocatedBytes("")
+}
 
-	return len(memoryBlocks) * blockSize / (1024 * 1024)
+// GetMappedMemoryMB returns the part of the allocated memory mapped outside the Go heap in MB
+func GetMappedMemoryMB() int {
+	memoryBlocksMutex.Lock()
+	defer memoryBlocksMutex.Unlock()
+	return int(memoryMapped / bytesPerMB)
 }
 
 // FreeAllMemory is a public function that can be called to explicitly free memory
 // even outside the normal benchmark stop flow
 func FreeAllMemory() {
-	freeMemory()
+	freeMemory("")
+}
+
+// FreeJobMemory releases the memory allocated by a single job, leaving other jobs untouched
+// Returns the released amount in MB
+func FreeJobMemory(jobID string) int {
+	return freeMemory(jobID)
 }
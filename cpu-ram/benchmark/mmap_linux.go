@@ -0,0 +1,44 @@
+//go:build linux
+
+package benchmark
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// mmapBlock maps anonymous private memory outside the Go heap, applies the huge page advice
+// and optionally locks the pages in memory
+func mmapBlock(size int, hugePages string, lock bool) ([]byte, error) {
+	data, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return nil, fmt.Errorf("mmap of %d KB failed: %w", size/1024, err)
+	}
+
+	advice := -1
+	switch hugePages {
+	case HugePagesOn:
+		advice = syscall.MADV_HUGEPAGE
+	case HugePagesOff:
+		advice = syscall.MADV_NOHUGEPAGE
+	}
+	if advice >= 0 {
+		if err := syscall.Madvise(data, advice); err != nil {
+			syscall.Munmap(data)
+			return nil, fmt.Errorf("madvise %s failed, transparent huge pages may not be supported: %w", hugePages, err)
+		}
+	}
+
+	if lock {
+		if err := syscall.Mlock(data); err != nil {
+			syscall.Munmap(data)
+			return nil, fmt.Errorf("mlock of %d KB failed, check the locked memory limit (ulimit -l) or CAP_IPC_LOCK: %w", size/1024, err)
+		}
+	}
+	return data, nil
+}
+
+// munmapBlock unmaps a block returned by mmapBlock, which also unlocks it
+func munmapBlock(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package benchmark
+
+import "errors"
+
+// errMmapUnsupported is returned for mmap blocks on platforms other than Linux
+var errMmapUnsupported = errors.New("the mmap backend is only supported on Linux")
+
+// mmapBlock is not supported outside Linux
+func mmapBlock(size int, hugePages string, lock bool) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+// munmapBlock is not supported outside Linux
+func munmapBlock(data []byte) error {
+	return errMmapUnsupported
+}
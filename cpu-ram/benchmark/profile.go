@@ -0,0 +1,225 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Supported load profile waveforms
+const (
+	ProfileRamp     = "ramp"     // Linear rise from min to max over one period, then holds max
+	ProfileStep     = "step"     // Holds min for one period, then jumps to max
+	ProfileSine     = "sine"     // Sine wave oscillating between min and max
+	ProfileSquare   = "square"   // Alternates between max and min every half period
+	ProfileSawtooth = "sawtooth" // Repeated linear rise from min to max every period
+)
+
+// profileTickInterval is how often the profile runner updates the CPU load
+const profileTickInterval = 250 * time.Millisecond
+
+// LoadProfile describes a time-varying CPU load waveform.
+// Min and Max are expressed in cores of load (e.g. 0.5 to 2.5).
+// A zero Duration runs the profile until it is explicitly stopped.
+type LoadProfile struct {
+	Type     string
+	Min      float64
+	Max      float64
+	Period   time.Duration
+	Duration time.Duration
+}
+
+// Validate checks that the profile describes a waveform the runner can generate
+func (p LoadProfile) Validate() error {
+	switch p.Type {
+	case ProfileRamp, ProfileStep, ProfileSine, ProfileSquare, ProfileSawtooth:
+	default:
+		return fmt.Errorf("unknown profile type %q", p.Type)
+	}
+
+	if p.Min < 0 || p.Max <= 0 || p.Min > p.Max {
+		return fmt.Errorf("invalid load range %.2f-%.2f cores", p.Min, p.Max)
+	}
+	if p.Period <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+	if p.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+
+	return nil
+}
+
+// valueAt returns the load in cores the profile prescribes after the given elapsed time
+func (p LoadProfile) valueAt(elapsed time.Duration) float64 {
+	span := p.Max - p.Min
+	phase := elapsed.Seconds() / p.Period.Seconds()
+
+	switch p.Type {
+	case ProfileRamp:
+		return p.Min + span*math.Min(phase, 1)
+	case ProfileStep:
+		if phase < 1 {
+			return p.Min
+		}
+		return p.Max
+	case ProfileSine:
+		return p.Min + span/2 + span/2*math.Sin(2*math.Pi*phase)
+	case ProfileSquare:
+		if phase-math.Floor(phase) < 0.5 {
+			return p.Max
+		}
+		return p.Min
+	case ProfileSawtooth:
+		return p.Min + span*(phase-math.Floor(phase))
+	}
+
+	return p.Min
+}
+
+// profileJob drives the load of its own CPU task along a profile
+type profileJob struct {
+	profile   LoadProfile
+	task      *cpuTask
+	job       *Job // Set before the runner starts
+	startTime time.Time
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex sync.Mutex
+	load  float64 // Load most recently applied by the profile, in cores
+}
+
+// run drives the CPU task load along the profile until stopped or the duration elapses
+func (p *profileJob) run() {
+	defer p.wg.Done()
+
+	profile := p.profile
+	ticker := time.NewTicker(profileTickInterval)
+	defer ticker.Stop()
+
+	fmt.Printf("CPU load profile %s started - %s between %.2f and %.2f cores, period %s\n",
+		p.job.id, profile.Type, profile.Min, profile.Max, profile.Period)
+
+	finish := func(reason string) {
+		p.task.stop()
+
+		p.mutex.Lock()
+		p.load = 0
+		p.mutex.Unlock()
+
+		fmt.Printf("CPU load profile %s stopped (%s)\n", p.job.id, reason)
+	}
+
+	for {
+		select {
+		case <-p.stopChan:
+			finish("stop requested")
+			return
+
+		case <-ticker.C:
+			elapsed := time.Since(p.startTime)
+			if profile.Duration > 0 && elapsed >= profile.Duration {
+				finish("duration elapsed")
+				p.job.complete("profile duration elapsed")
+				return
+			}
+
+			load := profile.valueAt(elapsed)
+			p.task.setLoad(load)
+
+			p.mutex.Lock()
+			p.load = load
+			p.mutex.Unlock()
+		}
+	}
+}
+
+// stop signals the profile runner to stop and waits for it together with its CPU task
+func (p *profileJob) stop() {
+	select {
+	case p.stopChan <- true:
+	default:
+	}
+	p.wg.Wait()
+}
+
+// currentLoad returns the load most recently applied by the profile, in cores
+func (p *profileJob) currentLoad() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.load
+}
+
+// summary describes the profile in one line
+func (p *profileJob) summary() string {
+	text := fmt.Sprintf("%s between %.2f and %.2f cores, period %s - current load %.2f cores, elapsed %s",
+		p.profile.Type, p.profile.Min, p.profile.Max, p.profile.Period,
+		p.currentLoad(), time.Since(p.startTime).Round(time.Second))
+	if p.profile.Duration > 0 {
+		text += fmt.Sprintf(" of %s", p.profile.Duration)
+	}
+	return text
+}
+
+// details describes the live state of the profile for job views
+func (p *profileJob) details() map[string]interface{} {
+	details := p.task.details()
+	details["profile_load"] = p.currentLoad()
+	details["elapsed_seconds"] = time.Since(p.startTime).Seconds()
+	return details
+}
+
+func (p *profileJob) cpuTask() *cpuTask { return p.task }
+
+// StartProfile starts a profile job whose CPU load follows the given profile.
+// The profile must already be validated. Profiles run side by side with other jobs.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartProfile(profile LoadProfile) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	// Enough workers to reach the profile maximum, each running at the starting load share
+	cores := int(math.Ceil(profile.Max))
+	if availableCores := runtime.NumCPU(); cores > availableCores {
+		cores = availableCores
+	}
+	initialLoad := profile.valueAt(0)
+
+	runner := &profileJob{
+		profile:   profile,
+		task:      newCPUTask(cores, math.Max(initialLoad/float64(cores)*100, minDutyCycle*100), defaultKernel),
+		startTime: time.Now(),
+		stopChan:  make(chan bool, 1),
+		load:      initialLoad,
+	}
+	runner.job = registerJob(JobKindProfile, map[string]interface{}{
+		"type":     profile.Type,
+		"min":      profile.Min,
+		"max":      profile.Max,
+		"period":   profile.Period.String(),
+		"duration": profile.Duration.String(),
+	}, runner)
+
+	runner.task.start()
+	runner.wg.Add(1)
+	go runner.run()
+
+	return runner.job.id, nil
+}
+
+// StopProfile stops all running load profiles together with their CPU tasks
+// Returns true if any profile was stopped, false if none was running
+func StopProfile() bool {
+	return StopJobsOfKind(JobKindProfile) > 0
+}
+
+// IsProfileRunning returns whether any CPU load profile is currently active
+func IsProfileRunning() bool {
+	return CountRunningJobs(JobKindProfile) > 0
+}
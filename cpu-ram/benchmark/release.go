@@ -0,0 +1,219 @@
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// releaseTickInterval is how often a rate-limited release drops blocks
+const releaseTickInterval = 250 * time.Millisecond
+
+// ReleaseOptions describes how allocated memory should be given back
+type ReleaseOptions struct {
+	Owner        string  // Job whose memory is released, empty releases the newest blocks of any job
+	TargetMB     int     // Size the allocation shrinks to
+	RateMBps     float64 // Release rate in MB per second, 0 releases everything at once
+	FreeOSMemory bool    // Call debug.FreeOSMemory after releasing instead of only collecting garbage
+}
+
+// HeapStats shows how much of the Go heap is in use and how much was returned to the OS
+type HeapStats struct {
+	InUseMB    float64 `json:"in_use_mb"`   // Heap spans holding live or not yet swept objects
+	IdleMB     float64 `json:"idle_mb"`     // Heap spans without objects, retained or released
+	ReleasedMB float64 `json:"released_mb"` // Idle spans returned to the OS
+	SysMB      float64 `json:"sys_mb"`      // Total memory obtained from the OS
+	RetainedMB float64 `json:"retained_mb"` // Idle spans not yet returned to the OS
+}
+
+// ReleaseResult reports the effect of an immediate release
+type ReleaseResult struct {
+	ReleasedMB  int       `json:"released_mb"`
+	AllocatedMB int       `json:"allocated_mb"` // Remaining allocation of the owner, or of all jobs
+	Before      HeapStats `json:"heap_before"`
+	After       HeapStats `json:"heap_after"`
+}
+
+// GetHeapStats reads the current heap statistics of the runtime
+func GetHeapStats() HeapStats {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	toMB := func(bytes uint64) float64 { return float64(bytes) / bytesPerMB }
+	return HeapStats{
+		InUseMB:    toMB(stats.HeapInuse),
+		IdleMB:     toMB(stats.HeapIdle),
+		ReleasedMB: toMB(stats.HeapReleased),
+		SysMB:      toMB(stats.Sys),
+		RetainedMB: toMB(stats.HeapIdle - stats.HeapReleased),
+	}
+}
+
+// allocatedBytes returns the memory held by the given job, or by all jobs if owner is empty
+func allocatedBytes(owner string) int64 {
+	if owner == "" {
+		memoryBlocksMutex.Lock()
+		defer memoryBlocksMutex.Unlock()
+		return memoryTotal
+	}
+	return ownedMemoryBytes(owner)
+}
+
+// reclaimMemory makes the garbage collector reclaim dropped blocks, and optionally
+// forces the runtime to return the freed pages to the OS right away
+func reclaimMemory(freeOSMemory bool) {
+	if freeOSMemory {
+		debug.FreeOSMemory()
+		return
+	}
+	runtime.GC()
+}
+
+// ReleaseMemory shrinks the allocation of a job, or of all jobs, to the target size at once.
+// The most recently allocated blocks are released first.
+func ReleaseMemory(options ReleaseOptions) ReleaseResult {
+	before := GetHeapStats()
+	target := int64(options.TargetMB) * bytesPerMB
+	released := dropMemoryBlocks(options.Owner, target)
+	reclaimMemory(options.FreeOSMemory)
+
+	result := ReleaseResult{
+		ReleasedMB:  int(released / bytesPerMB),
+		AllocatedMB: int(allocatedBytes(options.Owner) / bytesPerMB),
+		Before:      before,
+		After:       GetHeapStats(),
+	}
+
+	recordEvent("memory.release", map[string]interface{}{
+		"job":            options.Owner,
+		"released_mb":    result.ReleasedMB,
+		"allocated_mb":   result.AllocatedMB,
+		"free_os_memory": options.FreeOSMemory,
+		"heap_released":  result.After.ReleasedMB - result.Before.ReleasedMB,
+	}, "Released %d MB of memory, %d MB remain allocated", result.ReleasedMB, result.AllocatedMB)
+
+	return result
+}
+
+// releaseJob gradually shrinks an allocation at a fixed rate
+type releaseJob struct {
+	options   ReleaseOptions
+	start     int64 // Allocation when the release started, in bytes
+	target    int64
+	job       *Job // Set before the runner starts
+	startTime time.Time
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex    sync.Mutex
+	released int64
+}
+
+// run drops blocks along the rate until the target is reached or it is stopped
+func (r *releaseJob) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(releaseTickInterval)
+	defer ticker.Stop()
+
+	fmt.Printf("Memory release %s started - shrinking from %d MB to %d MB at %.1f MB/s\n",
+		r.job.id, r.start/bytesPerMB, r.target/bytesPerMB, r.options.RateMBps)
+
+	for {
+		select {
+		case <-r.stopChan:
+			fmt.Printf("Memory release %s stopped after releasing %d MB\n", r.job.id, r.releasedBytes()/bytesPerMB)
+			return
+
+		case <-ticker.C:
+			// Allocation the rate prescribes for the elapsed time, but never below the target
+			step := r.start - int64(r.options.RateMBps*time.Since(r.startTime).Seconds()*bytesPerMB)
+			if step < r.target {
+				step = r.target
+			}
+
+			if dropped := dropMemoryBlocks(r.options.Owner, step); dropped > 0 {
+				reclaimMemory(r.options.FreeOSMemory)
+				r.mutex.Lock()
+				r.released += dropped
+				r.mutex.Unlock()
+			}
+
+			if allocatedBytes(r.options.Owner) <= r.target {
+				heap := GetHeapStats()
+				fmt.Printf("Memory release %s reached %d MB after %s (%.1f MB of heap returned to the OS)\n",
+					r.job.id, r.target/bytesPerMB, time.Since(r.startTime).Round(time.Millisecond), heap.ReleasedMB)
+				r.job.complete(fmt.Sprintf("shrunk to %d MB", r.target/bytesPerMB))
+				return
+			}
+		}
+	}
+}
+
+// stop signals the release to stop and waits for it; already released memory stays released
+func (r *releaseJob) stop() {
+	select {
+	case r.stopChan <- true:
+	default:
+	}
+	r.wg.Wait()
+}
+
+// releasedBytes returns the memory released so far
+func (r *releaseJob) releasedBytes() int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.released
+}
+
+// summary describes the release in one line
+func (r *releaseJob) summary() string {
+	owner := "all jobs"
+	if r.options.Owner != "" {
+		owner = r.options.Owner
+	}
+	return fmt.Sprintf("shrinking %s from %d MB to %d MB at %.1f MB/s - %d MB released, %d MB allocated",
+		owner, r.start/bytesPerMB, r.target/bytesPerMB, r.options.RateMBps,
+		r.releasedBytes()/bytesPerMB, allocatedBytes(r.options.Owner)/bytesPerMB)
+}
+
+// details describes the live state of the release for job views
+func (r *releaseJob) details() map[string]interface{} {
+	return map[string]interface{}{
+		"released_mb":  r.releasedBytes() / bytesPerMB,
+		"allocated_mb": allocatedBytes(r.options.Owner) / bytesPerMB,
+		"heap":         GetHeapStats(),
+	}
+}
+
+// StartMemoryRelease starts a release job that shrinks the allocation of a job, or of all jobs,
+// to the target size at the given rate. The most recently allocated blocks are released first.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartMemoryRelease(options ReleaseOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	runner := &releaseJob{
+		options:   options,
+		start:     allocatedBytes(options.Owner),
+		target:    int64(options.TargetMB) * bytesPerMB,
+		startTime: time.Now(),
+		stopChan:  make(chan bool, 1),
+	}
+	runner.job = registerJob(JobKindRelease, map[string]interface{}{
+		"owner":          options.Owner,
+		"target_mb":      options.TargetMB,
+		"rate_mbps":      options.RateMBps,
+		"free_os_memory": options.FreeOSMemory,
+	}, runner)
+
+	runner.wg.Add(1)
+	go runner.run()
+
+	return runner.job.id, nil
+}
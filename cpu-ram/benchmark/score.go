@@ -0,0 +1,216 @@
+package benchmark
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scoreWindow is the number of controller intervals the benchmark score is averaged over
+const scoreWindow = 30
+
+// WorkerThroughput is the measured throughput of one CPU worker
+type WorkerThroughput struct {
+	ID               int     `json:"id"`
+	OpsPerSecond     float64 `json:"ops_per_second"`      // Kernel batches per wall-clock second
+	OpsPerBusySecond float64 `json:"ops_per_busy_second"` // Kernel batches per second spent computing
+	TotalOps         uint64  `json:"total_ops"`
+	SlowdownPct      float64 `json:"slowdown_pct"` // Slowdown against the calibrated baseline
+}
+
+// CPUResults summarizes the throughput of a CPU task
+type CPUResults struct {
+	Running       bool               `json:"running"`
+	Kernel        string             `json:"kernel"`
+	Workers       int                `json:"workers"`
+	StartTime     time.Time          `json:"start_time"`
+	WindowSeconds float64            `json:"window_seconds"`
+	Samples       int                `json:"samples"`
+	OpsPerSecond  float64            `json:"ops_per_second"` // Total throughput over the last interval
+	Score         float64            `json:"score"`          // Mean total throughput over the window
+	ScoreStdDev   float64            `json:"score_stddev"`
+	ScorePerCore  float64            `json:"score_per_core"` // Mean throughput of one fully busy core over the window
+	TotalOps      uint64             `json:"total_ops"`
+	Baseline      float64            `json:"baseline_ops_per_second"` // Calibrated idle single-core throughput, 0 if not calibrated
+	SlowdownPct   float64            `json:"slowdown_pct"`            // Slowdown of score_per_core against the baseline
+	PerWorker     []WorkerThroughput `json:"per_worker"`
+}
+
+// throughputTracker turns the worker counters of one CPU task into rates and a windowed score
+type throughputTracker struct {
+	mutex    sync.Mutex
+	current  CPUResults
+	lastOps  map[int]uint64
+	lastBusy map[int]int64
+	lastTime time.Time
+	totals   []float64 // Total ops/s per interval, oldest first
+	perCore  []float64 // Ops per busy second per interval, oldest first
+
+	retired    []*cpuWorker // Workers removed by a resize since the last sample
+	retiredOps uint64       // Ops of removed workers, carried into the total
+}
+
+// reset starts measuring the task's workers
+func (t *throughputTracker) reset(kernelName string, workers []*cpuWorker) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.current = CPUResults{
+		Running:       true,
+		Kernel:        kernelName,
+		Workers:       len(workers),
+		StartTime:     now,
+		WindowSeconds: (scoreWindow * controlInterval).Seconds(),
+	}
+	t.lastOps = make(map[int]uint64)
+	t.lastBusy = make(map[int]int64)
+	t.lastTime = now
+	t.totals = nil
+	t.perCore = nil
+	t.retired = nil
+	t.retiredOps = 0
+}
+
+// retire keeps counting a worker removed by a resize, its last ops are picked up by the next sample
+func (t *throughputTracker) retire(w *cpuWorker) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.retired = append(t.retired, w)
+}
+
+// sample computes rates since the previous sample and updates the windowed score
+func (t *throughputTracker) sample(workers []*cpuWorker) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(t.lastTime).Seconds()
+	t.lastTime = now
+	if elapsed <= 0 {
+		return
+	}
+
+	baseline := getBaseline(t.current.Kernel)
+	perWorker := make([]WorkerThroughput, 0, len(workers))
+	var intervalOps, totalOps uint64
+	var intervalBusy int64
+	for _, w := range workers {
+		ops := atomic.LoadUint64(&w.ops)
+		busy := atomic.LoadInt64(&w.busyNanos)
+		deltaOps := ops - t.lastOps[w.id]
+		deltaBusy := busy - t.lastBusy[w.id]
+		t.lastOps[w.id] = ops
+		t.lastBusy[w.id] = busy
+
+		rate := WorkerThroughput{
+			ID:           w.id,
+			OpsPerSecond: float64(deltaOps) / elapsed,
+			TotalOps:     ops,
+		}
+		if deltaBusy > 0 {
+			rate.OpsPerBusySecond = float64(deltaOps) / time.Duration(deltaBusy).Seconds()
+			rate.SlowdownPct = slowdownPct(rate.OpsPerBusySecond, baseline)
+		}
+		perWorker = append(perWorker, rate)
+
+		intervalOps += deltaOps
+		intervalBusy += deltaBusy
+		totalOps += ops
+	}
+
+	// Removed workers stop within one batch, their final counts stay in the total
+	for _, w := range t.retired {
+		ops := atomic.LoadUint64(&w.ops)
+		intervalOps += ops - t.lastOps[w.id]
+		intervalBusy += atomic.LoadInt64(&w.busyNanos) - t.lastBusy[w.id]
+		t.retiredOps += ops
+		delete(t.lastOps, w.id)
+		delete(t.lastBusy, w.id)
+	}
+	t.retired = nil
+	totalOps += t.retiredOps
+
+	total := float64(intervalOps) / elapsed
+	t.totals = appendWindow(t.totals, total)
+	if intervalBusy > 0 {
+		t.perCore = appendWindow(t.perCore, float64(intervalOps)/time.Duration(intervalBusy).Seconds())
+	}
+
+	t.current.Workers = len(workers)
+	t.current.PerWorker = perWorker
+	t.current.OpsPerSecond = total
+	t.current.TotalOps = totalOps
+	t.current.Samples = len(t.totals)
+	t.current.Score, t.current.ScoreStdDev = meanStdDev(t.totals)
+	t.current.ScorePerCore, _ = meanStdDev(t.perCore)
+	t.current.Baseline = baseline
+	t.current.SlowdownPct = slowdownPct(t.current.ScorePerCore, baseline)
+}
+
+// finish marks the measured task as stopped, keeping its results available
+func (t *throughputTracker) finish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.current.Running = false
+}
+
+// results returns a copy of the current measurement
+func (t *throughputTracker) results() CPUResults {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	results := t.current
+	results.PerWorker = append([]WorkerThroughput(nil), results.PerWorker...)
+	return results
+}
+
+// appendWindow appends a sample and drops the oldest ones beyond the score window
+func appendWindow(samples []float64, value float64) []float64 {
+	samples = append(samples, value)
+	if len(samples) > scoreWindow {
+		samples = samples[len(samples)-scoreWindow:]
+	}
+	return samples
+}
+
+// meanStdDev returns the mean and standard deviation of the samples
+func meanStdDev(samples []float64) (float64, float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, v := range samples {
+		sum += v
+	}
+	mean := sum / float64(len(samples))
+
+	variance := 0.0
+	for _, v := range samples {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(samples)))
+}
+
+// GetCPUResults returns the throughput of the CPU load of a job.
+// An empty job ID selects the most recently started job that generates CPU load.
+// Returns false if there is no such job.
+func GetCPUResults(jobID string) (CPUResults, bool) {
+	if jobID == "" {
+		all := jobsOfKind("", false)
+		for i := len(all) - 1; i >= 0; i-- {
+			if _, ok := all[i].runner.(cpuTaskOwner); ok {
+				jobID = all[i].id
+				break
+			}
+		}
+	}
+
+	task, ok := lookupCPUTask(jobID)
+	if !ok {
+		return CPUResults{}, false
+	}
+	return task.throughput.results(), true
+}
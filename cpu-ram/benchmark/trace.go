@@ -0,0 +1,375 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceTickInterval is how often the trace runner updates the CPU and memory tasks
+const traceTickInterval = 250 * time.Millisecond
+
+// Errors returned when a trace file cannot be loaded
+var (
+	ErrTracePath     = errors.New("trace path must be relative to the trace directory and stay inside it")
+	ErrTraceNotFound = errors.New("trace file not found in the trace directory")
+)
+
+// TraceSample is one row of a utilization trace.
+// CPU is a fraction of all available cores (0-1), Offset is relative to the first sample.
+type TraceSample struct {
+	Offset   time.Duration
+	CPU      float64
+	MemoryMB float64
+}
+
+// Trace is a sequence of samples ordered by strictly increasing offset
+type Trace []TraceSample
+
+// TraceOptions control how a trace is replayed
+type TraceOptions struct {
+	Speedup float64 // Trace seconds replayed per wall-clock second
+	Loop    bool    // Restart from the beginning when the end is reached
+}
+
+// ParseTrace reads a CSV trace with the columns timestamp (seconds), cpu fraction and memory MB.
+// A header row is skipped if present. Timestamps may be absolute or relative.
+func ParseTrace(r io.Reader) (Trace, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var trace Trace
+	var first float64
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		// Errors name the line and column only, so the contents of the trace are never echoed back
+		values := make([]float64, len(record))
+		column, numbers := 0, 0
+		for i, field := range record {
+			value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				if column == 0 {
+					column = i + 1
+				}
+				continue
+			}
+			values[i] = value
+			numbers++
+		}
+		if column != 0 {
+			// Only a first line without any number is taken for a header
+			if line == 1 && numbers == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: column %d is not a number", line, column)
+		}
+
+		if len(trace) == 0 {
+			first = values[0]
+		}
+		sample := TraceSample{
+			Offset:   time.Duration((values[0] - first) * float64(time.Second)),
+			CPU:      values[1],
+			MemoryMB: values[2],
+		}
+		if sample.CPU < 0 || sample.MemoryMB < 0 {
+			return nil, fmt.Errorf("line %d: negative utilization", line)
+		}
+		if sample.CPU > 1 {
+			return nil, fmt.Errorf("line %d: cpu must be a fraction of all cores from 0 to 1, not a percentage", line)
+		}
+		if len(trace) > 0 && sample.Offset <= trace[len(trace)-1].Offset {
+			return nil, fmt.Errorf("line %d: timestamps must be strictly increasing", line)
+		}
+		trace = append(trace, sample)
+	}
+
+	if len(trace) < 2 {
+		return nil, fmt.Errorf("trace needs at least two samples, got %d", len(trace))
+	}
+
+	return trace, nil
+}
+
+// leavesDir returns whether a cleaned relative path points outside of its directory
+func leavesDir(path string) bool {
+	return path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+// LoadTraceFile reads and parses a CSV trace from a file within the trace directory, e.g. a mounted volume.
+// The name must be relative and must not leave the directory, neither with .. nor through a symlink.
+func LoadTraceFile(dir, name string) (Trace, error) {
+	name = filepath.Clean(name)
+	if dir == "" || filepath.IsAbs(name) || leavesDir(name) {
+		return nil, ErrTracePath
+	}
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, ErrTraceNotFound
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if err != nil {
+		return nil, ErrTraceNotFound
+	}
+	if relative, err := filepath.Rel(root, path); err != nil || leavesDir(relative) {
+		return nil, ErrTracePath
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, ErrTraceNotFound
+	}
+	defer file.Close()
+
+	return ParseTrace(file)
+}
+
+// Length returns the time span covered by the trace
+func (t Trace) Length() time.Duration {
+	return t[len(t)-1].Offset - t[0].Offset
+}
+
+// maxCPU returns the highest CPU fraction in the trace
+func (t Trace) maxCPU() float64 {
+	max := 0.0
+	for _, sample := range t {
+		max = math.Max(max, sample.CPU)
+	}
+	return max
+}
+
+// valueAt linearly interpolates the CPU fraction and memory MB at the given offset
+func (t Trace) valueAt(offset time.Duration) (float64, float64) {
+	// Index of the first sample after the offset
+	i := sort.Search(len(t), func(i int) bool { return t[i].Offset > offset })
+	if i == 0 {
+		return t[0].CPU, t[0].MemoryMB
+	}
+	if i == len(t) {
+		last := t[len(t)-1]
+		return last.CPU, last.MemoryMB
+	}
+
+	prev, next := t[i-1], t[i]
+	ratio := float64(offset-prev.Offset) / float64(next.Offset-prev.Offset)
+	return prev.CPU + ratio*(next.CPU-prev.CPU), prev.MemoryMB + ratio*(next.MemoryMB-prev.MemoryMB)
+}
+
+// traceJob replays a trace through its own CPU task and memory blocks
+type traceJob struct {
+	trace     Trace
+	options   TraceOptions
+	task      *cpuTask
+	job       *Job   // Set before the runner starts
+	seed      uint64 // Seed of the fill pattern of the replayed memory
+	startTime time.Time
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	mutex    sync.Mutex
+	position time.Duration // Position within the trace most recently replayed
+	loops    int           // Number of completed passes over the trace
+	cpuLoad  float64       // CPU load most recently applied, in cores
+	memoryMB int           // Memory allocation most recently applied
+}
+
+// run replays the trace until stopped or, unless looping, the end of the trace is reached
+func (r *traceJob) run() {
+	defer r.wg.Done()
+
+	availableCores := runtime.NumCPU()
+	length := r.trace.Length()
+	ticker := time.NewTicker(traceTickInterval)
+	defer ticker.Stop()
+
+	fmt.Printf("Trace replay %s started - %d samples spanning %s at %.1fx speed\n",
+		r.job.id, len(r.trace), length, r.options.Speedup)
+
+	finish := func(reason string) {
+		r.task.stop()
+		fmt.Printf("Trace replay %s stopped (%s) - %d MB still allocated, use /memory/free to release\n",
+			r.job.id, reason, ownedMemoryMB(r.job.id))
+	}
+
+	for {
+		select {
+		case <-r.stopChan:
+			finish("stop requested")
+			return
+
+		case <-ticker.C:
+			position := time.Duration(float64(time.Since(r.startTime)) * r.options.Speedup)
+			loops := int(position / length)
+			if loops > 0 {
+				if !r.options.Loop {
+					finish("end of trace reached")
+					r.job.complete("end of trace reached")
+					return
+				}
+				position %= length
+			}
+
+			cpu, memoryMB := r.trace.valueAt(position)
+			load := cpu * float64(availableCores)
+			r.task.setLoad(load)
+			allocatedMB, stopped := resizeMemory(r.job.id, int(memoryMB), r.seed, r.stopChan)
+			if stopped {
+				finish("stop requested")
+				return
+			}
+
+			r.mutex.Lock()
+			r.position = position
+			r.loops = loops
+			r.cpuLoad = load
+			r.memoryMB = allocatedMB
+			r.mutex.Unlock()
+		}
+	}
+}
+
+// stop signals the trace runner to stop and waits for it together with its CPU task.
+// Replayed memory stays allocated.
+func (r *traceJob) stop() {
+	select {
+	case r.stopChan <- true:
+	default:
+	}
+	r.wg.Wait()
+}
+
+// status returns the progress of the replay
+func (r *traceJob) status() TraceStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return TraceStatus{
+		Samples:  len(r.trace),
+		Length:   r.trace.Length(),
+		Options:  r.options,
+		Position: r.position,
+		Loops:    r.loops,
+		CPULoad:  r.cpuLoad,
+		MemoryMB: r.memoryMB,
+		Elapsed:  time.Since(r.startTime),
+	}
+}
+
+// summary describes the replay in one line
+func (r *traceJob) summary() string {
+	status := r.status()
+	text := fmt.Sprintf("%d samples, position %s of %s at %.1fx speed - %.2f cores, %d MB",
+		status.Samples, status.Position.Round(time.Second), status.Length, status.Options.Speedup,
+		status.CPULoad, status.MemoryMB)
+	if status.Options.Loop {
+		text += fmt.Sprintf(", looping (%d passes completed)", status.Loops)
+	}
+	return text
+}
+
+// details describes the live state of the replay for job views
+func (r *traceJob) details() map[string]interface{} {
+	status := r.status()
+	details := r.task.details()
+	details["samples"] = status.Samples
+	details["length_seconds"] = status.Length.Seconds()
+	details["position_seconds"] = status.Position.Seconds()
+	details["loops"] = status.Loops
+	details["trace_load"] = status.CPULoad
+	details["memory_mb"] = status.MemoryMB
+	return details
+}
+
+func (r *traceJob) cpuTask() *cpuTask { return r.task }
+
+// StartTrace starts a trace job replaying the trace through its own CPU task and memory blocks.
+// Traces run side by side with other jobs, their load adds up.
+// Returns the ID of the new job, or ErrCalibrating while a calibration runs.
+func StartTrace(trace Trace, options TraceOptions) (string, error) {
+	if err := beginJobStart(); err != nil {
+		return "", err
+	}
+	defer endJobStart()
+
+	if options.Speedup <= 0 {
+		options.Speedup = 1
+	}
+
+	// Enough workers to reach the trace maximum, but never more than there are cores
+	availableCores := runtime.NumCPU()
+	cores := int(math.Ceil(trace.maxCPU() * float64(availableCores)))
+	if cores < 1 {
+		cores = 1
+	} else if cores > availableCores {
+		cores = availableCores
+	}
+	initialCPU, _ := trace.valueAt(0)
+	initialUtilization := math.Max(initialCPU*float64(availableCores)/float64(cores)*100, minDutyCycle*100)
+
+	runner := &traceJob{
+		trace:     trace,
+		options:   options,
+		task:      newCPUTask(cores, initialUtilization, defaultKernel),
+		seed:      uint64(rand.Int63()),
+		startTime: time.Now(),
+		stopChan:  make(chan bool, 1),
+	}
+	runner.job = registerJob(JobKindTrace, map[string]interface{}{
+		"samples":        len(trace),
+		"length_seconds": trace.Length().Seconds(),
+		"speedup":        options.Speedup,
+		"loop":           options.Loop,
+	}, runner)
+
+	runner.task.start()
+	runner.wg.Add(1)
+	go runner.run()
+
+	return runner.job.id, nil
+}
+
+// StopTrace stops all running trace replays and their CPU tasks; replayed memory stays allocated
+// Returns true if any replay was stopped, false if none was running
+func StopTrace() bool {
+	return StopJobsOfKind(JobKindTrace) > 0
+}
+
+// IsTraceRunning returns whether any trace replay is currently active
+func IsTraceRunning() bool {
+	return CountRunningJobs(JobKindTrace) > 0
+}
+
+// TraceStatus describes the progress of a trace replay
+type TraceStatus struct {
+	Samples  int
+	Length   time.Duration
+	Options  TraceOptions
+	Position time.Duration
+	Loops    int
+	CPULoad  float64
+	MemoryMB int
+	Elapsed  time.Duration
+}
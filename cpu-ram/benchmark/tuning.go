@@ -0,0 +1,118 @@
+package benchmark
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// RuntimeSettings are the Go runtime parameters that can be changed while the server runs
+type RuntimeSettings struct {
+	GCPercent        int   `json:"gc_percent"`         // GOGC, -1 if the garbage collector is off
+	MemoryLimitBytes int64 `json:"memory_limit_bytes"` // GOMEMLIMIT, the soft memory limit, 0 if there is none
+	GOMAXPROCS       int   `json:"gomaxprocs"`         // Threads that may run Go code at the same time
+	NumCPU           int   `json:"num_cpu"`            // CPUs the process may run on
+}
+
+// FreeOSMemoryResult reports the heap before and after returning as much memory as possible to the OS
+type FreeOSMemoryResult struct {
+	DurationSeconds float64   `json:"duration_seconds"`
+	Before          HeapStats `json:"heap_before"`
+	After           HeapStats `json:"heap_after"`
+}
+
+// gcPercent tracks GOGC, which the runtime only reports when it is changed.
+// Guarded by tuningMutex, which also serializes changes.
+var (
+	gcPercent   int
+	tuningMutex sync.Mutex
+)
+
+// init reads the GOGC setting the process was started with
+func init() {
+	gcPercent = debug.SetGCPercent(100)
+	debug.SetGCPercent(gcPercent)
+}
+
+// GetRuntimeSettings returns the current Go runtime parameters
+func GetRuntimeSettings() RuntimeSettings {
+	tuningMutex.Lock()
+	defer tuningMutex.Unlock()
+
+	settings := RuntimeSettings{
+		GCPercent:  gcPercent,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+	}
+	// A negative limit only reads the current one, math.MaxInt64 means there is none
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		settings.MemoryLimitBytes = limit
+	}
+	return settings
+}
+
+// SetGCPercent sets GOGC, -1 turns the garbage collector off. Returns the previous value.
+func SetGCPercent(percent int) int {
+	tuningMutex.Lock()
+	previous := debug.SetGCPercent(percent)
+	gcPercent = percent
+	tuningMutex.Unlock()
+
+	recordEvent("runtime.gc_percent", map[string]interface{}{
+		"gc_percent": percent,
+		"previous":   previous,
+	}, "GOGC changed from %d to %d", previous, percent)
+	return previous
+}
+
+// SetMemoryLimit sets the soft memory limit of the runtime in bytes, 0 removes it.
+// Returns the previous limit, 0 if there was none.
+func SetMemoryLimit(limit int64) int64 {
+	tuningMutex.Lock()
+	applied := limit
+	if applied == 0 {
+		applied = math.MaxInt64
+	}
+	previous := debug.SetMemoryLimit(applied)
+	if previous == math.MaxInt64 {
+		previous = 0
+	}
+	tuningMutex.Unlock()
+
+	recordEvent("runtime.memory_limit", map[string]interface{}{
+		"memory_limit_mb": limit / bytesPerMB,
+		"previous_mb":     previous / bytesPerMB,
+	}, "Soft memory limit changed from %d MB to %d MB (0 = none)", previous/bytesPerMB, limit/bytesPerMB)
+	return previous
+}
+
+// SetMaxProcs sets GOMAXPROCS. Returns the previous value.
+func SetMaxProcs(procs int) int {
+	tuningMutex.Lock()
+	previous := runtime.GOMAXPROCS(procs)
+	tuningMutex.Unlock()
+
+	recordEvent("runtime.gomaxprocs", map[string]interface{}{
+		"gomaxprocs": procs,
+		"previous":   previous,
+	}, "GOMAXPROCS changed from %d to %d", previous, procs)
+	return previous
+}
+
+// FreeOSMemory forces a garbage collection and returns as much memory to the OS as possible
+func FreeOSMemory() FreeOSMemoryResult {
+	start := time.Now()
+	result := FreeOSMemoryResult{Before: GetHeapStats()}
+	debug.FreeOSMemory()
+	result.After = GetHeapStats()
+	result.DurationSeconds = time.Since(start).Seconds()
+
+	recordEvent("runtime.free_os_memory", map[string]interface{}{
+		"released_mb": result.After.ReleasedMB - result.Before.ReleasedMB,
+		"seconds":     result.DurationSeconds,
+	}, "Returned %.1f MB of the heap to the OS in %s",
+		result.After.ReleasedMB-result.Before.ReleasedMB, time.Since(start).Round(time.Millisecond))
+	return result
+}
@@ -0,0 +1,234 @@
+package benchmark
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Page walker settings
+const (
+	pageSize          = 4096
+	walkTickInterval  = 10 * time.Millisecond // How often the walker catches up with its rate
+	walkRefreshPeriod = time.Second           // How often the walked blocks and measured rate are refreshed
+)
+
+// pageWalker touches pages of the allocated memory blocks at a fixed rate
+type pageWalker struct {
+	owner    string  // Blocks walked, empty walks the blocks of all jobs
+	fraction float64 // Share of the blocks walked, oldest first
+	rateMBps float64 // Pages touched per second, expressed in MB
+	write    bool    // Modify every touched page instead of only reading it
+	random   bool    // Touch randomly chosen pages instead of walking them in order
+
+	stopChan chan bool
+	wg       sync.WaitGroup
+
+	// Walked blocks and position, only used by the walker goroutine
+	blocks []*memoryBlock
+	block  int
+	offset int
+	rng    *rand.Rand
+	sink   byte // Sum of the read bytes, keeps the reads from being optimized away
+
+	touched  uint64 // Pages touched so far, updated atomically
+	passes   uint64 // Completed sequential passes over the walked blocks, updated atomically
+	measured uint64 // Touch rate measured over the last refresh period in MB/s, stored as bits
+}
+
+// newPageWalker creates a walker; it does not run until started
+func newPageWalker(owner string, fraction, rateMBps float64, write, random bool) *pageWalker {
+	return &pageWalker{
+		owner:    owner,
+		fraction: fraction,
+		rateMBps: rateMBps,
+		write:    write,
+		random:   random,
+		stopChan: make(chan bool, 1),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// start runs the walker in the background
+func (w *pageWalker) start() {
+	w.wg.Add(1)
+	go w.run()
+}
+
+// stop signals the walker to stop and waits for it
+func (w *pageWalker) stop() {
+	select {
+	case w.stopChan <- true:
+	default:
+	}
+	w.wg.Wait()
+}
+
+// refreshBlocks takes a snapshot of the blocks to walk, so blocks allocated or
+// dropped in the meantime are picked up without holding the pool lock while walking
+func (w *pageWalker) refreshBlocks() {
+	memoryBlocksMutex.Lock()
+	blocks := make([]*memoryBlock, 0, len(memoryBlocks))
+	for _, block := range memoryBlocks {
+		if w.owner == "" || block.owner == w.owner {
+			blocks = append(blocks, block)
+		}
+	}
+	memoryBlocksMutex.Unlock()
+
+	count := int(math.Ceil(w.fraction * float64(len(blocks))))
+	if count > len(blocks) {
+		count = len(blocks)
+	}
+	w.blocks = blocks[:count]
+	if w.block >= len(w.blocks) {
+		w.block, w.offset = 0, 0
+	}
+}
+
+// lockBlock locks a block for touching it. Write walks take the lock exclusively,
+// so other writers and readers such as verification never see a byte while it changes.
+func (w *pageWalker) lockBlock(block *memoryBlock) {
+	if w.write {
+		block.mutex.Lock()
+	} else {
+		block.mutex.RLock()
+	}
+}
+
+// unlockBlock releases the lock taken by lockBlock
+func (w *pageWalker) unlockBlock(block *memoryBlock) {
+	if w.write {
+		block.mutex.Unlock()
+	} else {
+		block.mutex.RUnlock()
+	}
+}
+
+// touch reads or modifies one byte of a page, which is enough to keep the page resident and active.
+// Modified blocks are flagged, their fill pattern no longer matches its checksum.
+// The block must be locked with lockBlock.
+func (w *pageWalker) touch(block *memoryBlock, offset int) {
+	if w.write {
+		atomic.StoreUint32(&block.modified, 1)
+		block.data[offset]++
+	} else {
+		w.sink += block.data[offset]
+	}
+}
+
+// touchPages touches the given number of pages.
+// Returns false if there is nothing to walk.
+func (w *pageWalker) touchPages(pages int) bool {
+	if len(w.blocks) == 0 {
+		return false
+	}
+
+	// Blocks are only touched under their lock, a block released since the last refresh is empty and skipped
+	for i := 0; i < pages; i++ {
+		if w.random {
+			block := w.blocks[w.rng.Intn(len(w.blocks))]
+			w.lockBlock(block)
+			if len(block.data) > 0 {
+				w.touch(block, w.rng.Intn((len(block.data)+pageSize-1)/pageSize)*pageSize)
+			}
+			w.unlockBlock(block)
+			continue
+		}
+
+		block := w.blocks[w.block]
+		w.lockBlock(block)
+		size := len(block.data)
+		if w.offset < size {
+			w.touch(block, w.offset)
+		}
+		w.unlockBlock(block)
+		w.offset += pageSize
+		if w.offset >= size {
+			w.block, w.offset = w.block+1, 0
+			if w.block >= len(w.blocks) {
+				w.block = 0
+				atomic.AddUint64(&w.passes, 1)
+			}
+		}
+	}
+	atomic.AddUint64(&w.touched, uint64(pages))
+	return true
+}
+
+// run touches pages at the configured rate until signaled to stop
+func (w *pageWalker) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(walkTickInterval)
+	defer ticker.Stop()
+	refreshTicker := time.NewTicker(walkRefreshPeriod)
+	defer refreshTicker.Stop()
+
+	pagesPerSecond := w.rateMBps * bytesPerMB / pageSize
+	startTime := time.Now()
+	done := 0 // Pages touched since startTime
+
+	lastTouched, lastTime := uint64(0), startTime
+	w.refreshBlocks()
+
+	for {
+		select {
+		case <-w.stopChan:
+			return
+
+		case <-ticker.C:
+			// Catch up with the rate, but skip what is more than two ticks late instead of bursting
+			due := int(time.Since(startTime).Seconds() * pagesPerSecond)
+			if limit := int(2*pagesPerSecond*walkTickInterval.Seconds()) + 1; due-done > limit {
+				done = due - limit
+			}
+			pages := due - done
+			if pages <= 0 {
+				continue
+			}
+
+			// Do not build up a backlog while there is nothing to walk
+			if !w.touchPages(pages) {
+				startTime, done = time.Now(), 0
+				continue
+			}
+			done += pages
+
+		case <-refreshTicker.C:
+			w.refreshBlocks()
+
+			now := time.Now()
+			touched := atomic.LoadUint64(&w.touched)
+			rate := float64(touched-lastTouched) * pageSize / bytesPerMB / now.Sub(lastTime).Seconds()
+			atomic.StoreUint64(&w.measured, math.Float64bits(rate))
+			lastTouched, lastTime = touched, now
+		}
+	}
+}
+
+// walkedMB returns the size of the memory currently being walked
+func (w *pageWalker) walkedMB() float64 {
+	return w.fraction * float64(allocatedBytes(w.owner)) / bytesPerMB
+}
+
+// measuredRate returns the touch rate achieved over the last second in MB/s
+func (w *pageWalker) measuredRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&w.measured))
+}
+
+// details describes the live state of the walker
+func (w *pageWalker) details() map[string]interface{} {
+	return map[string]interface{}{
+		"fraction":         w.fraction,
+		"walked_mb":        w.walkedMB(),
+		"rate_mbps":        w.rateMBps,
+		"measured_mbps":    w.measuredRate(),
+		"write":            w.write,
+		"random":           w.random,
+		"pages_touched":    atomic.LoadUint64(&w.touched),
+		"passes_completed": atomic.LoadUint64(&w.passes),
+	}
+}
@@ -4,6 +4,13 @@ package config
 type AppConfig struct {
 	ServerPort string
 	ServerHost string
+
+	// CalibrateOnStartup measures the idle single-core kernel throughput at boot
+	// to provide a baseline for interference detection
+	CalibrateOnStartup bool
+
+	// TraceDir is the only directory trace files may be read from with ?path=, e.g. a mounted volume
+	TraceDir string
 }
 
 // GetDefaultConfig returns the default configuration
@@ -11,5 +18,9 @@ func GetDefaultConfig() AppConfig {
 	return AppConfig{
 		ServerPort: "8080",
 		ServerHost: "0.0.0.0",
+
+		CalibrateOnStartup: true,
+
+		TraceDir: "/data",
 	}
 }
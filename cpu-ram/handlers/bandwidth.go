@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"benchmarking/benchmark"
+)
+
+// BandwidthHandler returns the results of a bandwidth job as JSON (GET) or starts a new one (POST).
+// GET supports ?job=ID, by default the most recently started bandwidth job is reported.
+// POST supports ?size=MB per array (default 64), ?threads=N (default one per core of the cgroup CPU limit),
+// ?rounds=N to complete after N rounds, ?duration=D and ?lease=TTL
+func BandwidthHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		results, ok := benchmark.GetBandwidthResults(r.URL.Query().Get("job"))
+		if !ok {
+			http.Error(w, "No bandwidth benchmark results available", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.BandwidthOptions{}
+	if value := query.Get("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			http.Error(w, "Invalid size, must be a positive number of MB per array", http.StatusBadRequest)
+			return
+		}
+		options.ArrayMB = size
+	}
+	if value := query.Get("threads"); value != "" {
+		threads, err := strconv.Atoi(value)
+		if err != nil || threads <= 0 {
+			http.Error(w, "Invalid threads, must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		options.Threads = threads
+	}
+	if value := query.Get("rounds"); value != "" {
+		rounds, err := strconv.Atoi(value)
+		if err != nil || rounds <= 0 {
+			http.Error(w, "Invalid rounds, must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		options.Rounds = rounds
+	}
+
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
+		return
+	}
+	options.Duration = duration
+	leaseTTL, ok := parseLeaseParam(w, r)
+	if !ok {
+		return
+	}
+	options.LeaseTTL = leaseTTL
+
+	jobID, err := benchmark.StartBandwidth(options)
+	if errors.Is(err, benchmark.ErrBandwidthMemory) || errors.Is(err, benchmark.ErrCalibrating) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job, _ := benchmark.GetJob(jobID)
+
+	setJobHeaders(w, jobID)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Bandwidth job %s started: STREAM kernels over 3 arrays of %v MB with %v threads",
+		jobID, job.Params["array_mb"], job.Params["threads"])
+	if options.Rounds > 0 {
+		fmt.Fprintf(w, " for %d rounds", options.Rounds)
+	}
+	if duration > 0 {
+		fmt.Fprintf(w, " for %s", duration)
+	}
+	if job.LeaseID != "" {
+		fmt.Fprintf(w, " with lease %s (TTL %s)", job.LeaseID, leaseTTL)
+	}
+}
+
+// DeactivateBandwidthHandler stops all running bandwidth jobs
+func DeactivateBandwidthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !benchmark.StopBandwidth() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No bandwidth benchmark is currently running")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Bandwidth benchmarks deactivated successfully")
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"benchmarking/benchmark"
+)
+
+// CalibrateHandler returns the stored calibration baselines (GET) or runs a new calibration (POST).
+// POST accepts ?kernel=name[,name...] or ?kernel=all, defaulting to the math kernel.
+func CalibrateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, benchmark.GetCalibrations())
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var kernels []string
+	if value := r.URL.Query().Get("kernel"); value == "all" {
+		kernels = benchmark.KernelNames()
+	} else if value != "" {
+		kernels = strings.Split(value, ",")
+	}
+	for _, kernel := range kernels {
+		if !benchmark.IsValidKernel(kernel) {
+			http.Error(w, "Invalid kernel, must be one of: "+strings.Join(benchmark.KernelNames(), ", "),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := benchmark.Calibrate(kernels...)
+	if errors.Is(err, benchmark.ErrJobsRunning) || errors.Is(err, benchmark.ErrCalibrationBusy) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"benchmarking/benchmark"
+)
+
+// bytesPerMB converts byte counts for display
+const bytesPerMB = 1024 * 1024
+
+// CgroupHandler returns the CPU and memory limits of the container's cgroup as JSON
+// Supports GET /cgroup; the limits are read again on every request
+func CgroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.GetCgroupLimits())
+}
+
+// parseQuotaParam reads the optional ?quota= parameter, a share of the cgroup limit
+// given as a fraction (0.8) or a percentage (80%). Returns 0 if it is not set.
+func parseQuotaParam(w http.ResponseWriter, r *http.Request) (float64, bool) {
+	value := r.URL.Query().Get("quota")
+	if value == "" {
+		return 0, true
+	}
+
+	scale := 1.0
+	if strings.HasSuffix(value, "%") {
+		value, scale = strings.TrimSuffix(value, "%"), 100
+	}
+	quota, err := strconv.ParseFloat(value, 64)
+	quota /= scale
+	if err != nil || quota <= 0 || quota > 1 {
+		http.Error(w, "Invalid quota, must be a fraction in (0, 1] or a percentage such as 80%", http.StatusBadRequest)
+		return 0, false
+	}
+	return quota, true
+}
+
+// writeCgroupStatus writes the cgroup limits line of the status page
+func writeCgroupStatus(w http.ResponseWriter) {
+	limits := benchmark.GetCgroupLimits()
+	fmt.Fprintf(w, "- Cgroup v%d: ", limits.Version)
+
+	switch {
+	case !limits.CPUController:
+		fmt.Fprintf(w, "%d CPUs (no CPU controller)", limits.CPUs)
+	case limits.CPUQuota > 0:
+		fmt.Fprintf(w, "CPU limit %.2f cores (quota %.2f cores per %s on %d CPUs)",
+			limits.CPULimit, limits.CPUQuota, limits.CPUPeriod, limits.CPUs)
+	default:
+		fmt.Fprintf(w, "CPU limit %.0f cores (no quota)", limits.CPULimit)
+	}
+
+	switch {
+	case !limits.MemoryController:
+		fmt.Fprintf(w, ", %d MB host memory (no memory controller)", limits.HostMemoryMB)
+	case limits.MemoryLimitMB > 0:
+		fmt.Fprintf(w, ", memory limit %d MB (%d MB used)", limits.MemoryLimitMB, limits.MemoryUsageMB)
+	default:
+		fmt.Fprintf(w, ", no memory limit (%d MB host memory, %d MB used)", limits.HostMemoryMB, limits.MemoryUsageMB)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// formatCgroupDelta describes throttling, memory events and memory usage changes in one line.
+// Counters the cgroup version does not provide are left out.
+func formatCgroupDelta(delta benchmark.CgroupDelta) string {
+	parts := []string{}
+	if periods, ok := delta.Counters["cpu_periods"]; ok {
+		throttled := delta.Counters["cpu_throttled_periods"]
+		throttledTime := time.Duration(delta.Counters["cpu_throttled_usec"]) * time.Microsecond
+		parts = append(parts, fmt.Sprintf("%d of %d periods throttled (%s)", throttled, periods, throttledTime.Round(time.Millisecond)))
+	}
+	parts = append(parts, fmt.Sprintf("memory %+d MB (peak %d MB)",
+		delta.MemoryChangeBytes/bytesPerMB, delta.MemoryPeakBytes/bytesPerMB))
+	events := []string{}
+	for _, name := range []string{"high", "max", "oom", "oom_kill"} {
+		if count, ok := delta.Counters["memory_events_"+name]; ok {
+			events = append(events, fmt.Sprintf("%s=%d", name, count))
+		}
+	}
+	if len(events) > 0 {
+		parts = append(parts, "memory events "+strings.Join(events, " "))
+	}
+	if scanned, ok := delta.Counters["memory_pgscan"]; ok {
+		parts = append(parts, fmt.Sprintf("%d pages scanned, %d reclaimed", scanned, delta.Counters["memory_pgsteal"]))
+	}
+	if faults, ok := delta.Counters["memory_pgfault"]; ok {
+		parts = append(parts, fmt.Sprintf("%d page faults (%d major)", faults, delta.Counters["memory_pgmajfault"]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeCgroupActivity writes the cgroup activity over the last sampling interval, if it was sampled
+func writeCgroupActivity(w http.ResponseWriter) {
+	if activity, ok := benchmark.GetCgroupActivity(); ok {
+		fmt.Fprintf(w, "- Cgroup activity (last %.0fs): %s\n", activity.Seconds, formatCgroupDelta(activity))
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"benchmarking/benchmark"
+)
+
+// ChurnHandler returns the results of a churn job as JSON (GET) or starts a new one (POST).
+// GET supports ?job=ID, by default the most recently started churn job is reported.
+// POST supports ?live=MB (default 256), ?min=B and ?max=B object sizes in bytes (default 16 to 32768),
+// ?rate=R MB/s replaced (default 100), ?long_lived=F share of long-lived objects (default 0.5), ?duration=D and ?lease=TTL
+func ChurnHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		results, ok := benchmark.GetChurnResults(r.URL.Query().Get("job"))
+		if !ok {
+			http.Error(w, "No churn benchmark results available", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.ChurnOptions{}
+	if value := query.Get("live"); value != "" {
+		// Larger sizes would overflow when converted to bytes
+		live, err := strconv.Atoi(value)
+		if err != nil || live <= 0 || int64(live) > math.MaxInt64/bytesPerMB {
+			http.Error(w, fmt.Sprintf("Invalid live set size, must be a number of MB from 1 to %d", int64(math.MaxInt64/bytesPerMB)),
+				http.StatusBadRequest)
+			return
+		}
+		options.LiveMB = live
+	}
+	if value := query.Get("min"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 || size > benchmark.MaxChurnObjectSize {
+			http.Error(w, fmt.Sprintf("Invalid min, must be an object size from 1 to %d bytes", benchmark.MaxChurnObjectSize),
+				http.StatusBadRequest)
+			return
+		}
+		options.MinSize = size
+	}
+	if value := query.Get("max"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 || size > benchmark.MaxChurnObjectSize {
+			http.Error(w, fmt.Sprintf("Invalid max, must be an object size from 1 to %d bytes", benchmark.MaxChurnObjectSize),
+				http.StatusBadRequest)
+			return
+		}
+		options.MaxSize = size
+	}
+	if options.MinSize > 0 && options.MaxSize > 0 && options.MinSize > options.MaxSize {
+		http.Error(w, "Invalid object sizes, min must not exceed max", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("rate"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			http.Error(w, "Invalid rate, must be a positive number of MB per second", http.StatusBadRequest)
+			return
+		}
+		options.RateMBps = rate
+	}
+	if value := query.Get("long_lived"); value != "" {
+		share, err := strconv.ParseFloat(value, 64)
+		if err != nil || share <= 0 || share > 1 {
+			http.Error(w, "Invalid long_lived, must be a fraction in (0, 1]", http.StatusBadRequest)
+			return
+		}
+		options.LongLived = share
+	}
+
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
+		return
+	}
+	options.Duration = duration
+	leaseTTL, ok := parseLeaseParam(w, r)
+	if !ok {
+		return
+	}
+	options.LeaseTTL = leaseTTL
+
+	jobID, err := benchmark.StartChurn(options)
+	if errors.Is(err, benchmark.ErrChurnMemory) || errors.Is(err, benchmark.ErrCalibrating) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job, _ := benchmark.GetJob(jobID)
+
+	setJobHeaders(w, jobID)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Churn job %s started: %v MB live set of %v-%v byte objects, replacing %v MB/s",
+		jobID, job.Params["live_mb"], job.Params["min_size"], job.Params["max_size"], job.Params["rate_mbps"])
+	if duration > 0 {
+		fmt.Fprintf(w, " for %s", duration)
+	}
+	if job.LeaseID != "" {
+		fmt.Fprintf(w, " with lease %s (TTL %s)", job.LeaseID, leaseTTL)
+	}
+}
+
+// DeactivateChurnHandler stops all running churn jobs
+func DeactivateChurnHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !benchmark.StopChurn() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No churn benchmark is currently running")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Churn benchmarks deactivated successfully")
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"benchmarking/benchmark"
+)
+
+// DirtyHandler starts rewriting allocated memory pages at a fixed rate
+// Supports POST /memory/dirty with optional ?rate=R (MB/s, default 100), ?order=sequential|random,
+// ?job=ID to dirty only the memory of one job, ?duration=D and ?lease=TTL
+func DirtyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.DirtyOptions{Owner: query.Get("job")}
+	if value := query.Get("rate"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			http.Error(w, "Invalid rate, must be a positive number of MB per second", http.StatusBadRequest)
+			return
+		}
+		options.RateMBps = rate
+	}
+	switch query.Get("order") {
+	case "", "sequential":
+	case "random":
+		options.Random = true
+	default:
+		http.Error(w, "Invalid order, must be sequential or random", http.StatusBadRequest)
+		return
+	}
+
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
+		return
+	}
+	options.Duration = duration
+	leaseTTL, ok := parseLeaseParam(w, r)
+	if !ok {
+		return
+	}
+	options.LeaseTTL = leaseTTL
+
+	jobID, err := benchmark.StartDirtyPages(options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	job, _ := benchmark.GetJob(jobID)
+
+	setJobHeaders(w, jobID)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Dirty-page job %s started: rewriting %v MB/s of allocated memory", jobID, job.Params["rate_mbps"])
+	if options.Owner != "" {
+		fmt.Fprintf(w, " of job %s", options.Owner)
+	}
+	if benchmark.GetAllocatedMemoryMB() == 0 {
+		fmt.Fprintf(w, " (no memory allocated yet, pages are dirtied once it is)")
+	}
+	if duration > 0 {
+		fmt.Fprintf(w, " for %s", duration)
+	}
+	if job.LeaseID != "" {
+		fmt.Fprintf(w, " with lease %s (TTL %s)", job.LeaseID, leaseTTL)
+	}
+}
+
+// DeactivateDirtyHandler stops all running dirty-page generators
+func DeactivateDirtyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !benchmark.StopDirtyPages() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No dirty-page generator is currently running")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Dirty-page generators deactivated successfully")
+}
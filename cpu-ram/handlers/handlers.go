@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"benchmarking/benchmark"
@@ -13,6 +16,9 @@ import (
 // URL pattern for CPU activation with core count
 var cpuActivatePattern = regexp.MustCompile(`^/cpu/activate(?:/(\d+))?$`)
 
+// URL pattern for resizing the running CPU task
+var cpuResizePattern = regexp.MustCompile(`^/cpu/resize(?:/(\d+))?$`)
+
 // URL pattern for memory activation with memory limit
 var memoryActivatePattern = regexp.MustCompile(`^/memory/activate(?:/(\d+))?$`)
 
@@ -30,7 +36,8 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ActivateHandler handles CPU benchmark activation requests
-// Supports both /activate and /cpu/activate[/cores] paths
+// Supports both /activate and /cpu/activate[/cores] paths, with optional
+// ?utilization=P (percent per core) or ?load=C (total cores), ?kernel=name, ?duration=D and ?lease=TTL query parameters
 func ActivateHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -52,22 +59,82 @@ func ActivateHandler(w http.ResponseWriter, r *http.Request) {
 		cores = coreCount
 	}
 
-	if !benchmark.StartTaskWithCores(cores) {
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprintf(w, "CPU benchmark task is already running")
+	// Optional partial load: ?utilization=P keeps each core busy P% of the time,
+	// ?load=C generates C cores worth of load in total (e.g. 2.5),
+	// ?quota=F generates share F of the cgroup CPU limit in total (e.g. 0.5 or 50%)
+	// Optional ?kernel=name selects the workload kernel
+	query := r.URL.Query()
+	options := benchmark.CPUTaskOptions{Cores: cores}
+	if value := query.Get("utilization"); value != "" {
+		utilization, err := strconv.ParseFloat(value, 64)
+		if err != nil || utilization <= 0 || utilization > 100 {
+			http.Error(w, "Invalid utilization, must be in (0, 100]", http.StatusBadRequest)
+			return
+		}
+		options.Utilization = utilization
+	}
+	if value := query.Get("load"); value != "" {
+		load, err := strconv.ParseFloat(value, 64)
+		if err != nil || load <= 0 {
+			http.Error(w, "Invalid load, must be a positive number of cores", http.StatusBadRequest)
+			return
+		}
+		options.Load = load
+	}
+	quota, ok := parseQuotaParam(w, r)
+	if !ok {
+		return
+	}
+	if quota > 0 && options.Load > 0 {
+		http.Error(w, "Specify either load or quota", http.StatusBadRequest)
+		return
+	}
+	options.Quota = quota
+	if value := query.Get("kernel"); value != "" {
+		if !benchmark.IsValidKernel(value) {
+			http.Error(w, fmt.Sprintf("Invalid kernel, must be one of: %s",
+				strings.Join(benchmark.KernelNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		options.Kernel = value
+	}
+
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
 		return
 	}
+	options.Duration = duration
 
-	// Give the task a moment to start and update its internal state
-	time.Sleep(100 * time.Millisecond)
+	leaseTTL, ok := parseLeaseParam(w, r)
+	if !ok {
+		return
+	}
+	options.LeaseTTL = leaseTTL
 
-	// Now get the actual cores being used
-	coresUsed := benchmark.GetCPUCoresUsed()
+	jobID, err := benchmark.StartCPUTask(options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	job, _ := benchmark.GetJob(jobID)
+
+	setJobHeaders(w, jobID)
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "CPU benchmark task activated successfully using %d cores", coresUsed)
+	fmt.Fprintf(w, "CPU benchmark job %s activated successfully using %v cores at %.1f%% utilization with the %v kernel",
+		jobID, job.Params["cores"], job.Params["utilization"], job.Params["kernel"])
+	if quota > 0 {
+		fmt.Fprintf(w, " (%.0f%% of the CPU limit)", quota*100)
+	}
+	if duration > 0 {
+		fmt.Fprintf(w, " for %s", duration)
+	}
+	if job.LeaseID != "" {
+		fmt.Fprintf(w, " with lease %s (TTL %s)", job.LeaseID, leaseTTL)
+	}
 }
 
-// DeactivateHandler handles CPU benchmark deactivation requests
+// DeactivateHandler handles CPU benchmark deactivation requests by stopping all CPU jobs
+// Single jobs are stopped through /jobs/{id}/stop
 func DeactivateHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -81,11 +148,71 @@ func DeactivateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "CPU benchmark task deactivated successfully")
+	fmt.Fprintf(w, "CPU benchmark jobs deactivated successfully")
+}
+
+// ResizeHandler adds or removes workers of a running CPU job without restarting it
+// Supports PATCH (or POST) /cpu/resize/N for an absolute worker count and /cpu/resize?delta=±K.
+// The job is selected with ?job=ID, which may be omitted while only one job generates CPU load.
+func ResizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, ok := selectCPUJob(w, r)
+	if !ok {
+		return
+	}
+
+	matches := cpuResizePattern.FindStringSubmatch(r.URL.Path)
+	delta := r.URL.Query().Get("delta")
+
+	cores := 0
+	switch {
+	case len(matches) > 1 && matches[1] != "":
+		coreCount, err := strconv.Atoi(matches[1])
+		if err != nil || coreCount <= 0 {
+			http.Error(w, "Invalid core count", http.StatusBadRequest)
+			return
+		}
+		cores = coreCount
+	case delta != "":
+		change, err := strconv.Atoi(delta)
+		if err != nil {
+			http.Error(w, "Invalid delta", http.StatusBadRequest)
+			return
+		}
+		current, running := benchmark.GetCPUJobCores(jobID)
+		if !running {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, "Job %s is not generating CPU load", jobID)
+			return
+		}
+		cores = current + change
+		if cores <= 0 {
+			http.Error(w, "Resize would leave no workers, use /cpu/deactivate instead", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Missing core count or delta", http.StatusBadRequest)
+		return
+	}
+
+	previous, current, ok := benchmark.ResizeCPUTask(jobID, cores)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "Job %s is not generating CPU load", jobID)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "CPU benchmark job %s resized from %d to %d cores", jobID, previous, current)
 }
 
 // ActivateMemoryHandler handles memory benchmark activation requests
-// Supports /memory/activate[/limit] where limit is in MB (default: 1024 MB)
+// Supports /memory/activate[/limit] where limit is in MB (default: 1024 MB) or ?quota=F is a share of the memory limit,
+// with optional ?rate=R, ?block=B, ?seed=N, ?backend=heap|mmap, ?madvise=A, ?lock=true, ?cgroup_guard=P, ?hot=F, ?touch_rate=R, ?touch=M, ?duration=D, ?free=true and ?lease=TTL query parameters
 func ActivateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -99,27 +226,197 @@ func ActivateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	matches := memoryActivatePattern.FindStringSubmatch(r.URL.Path)
 	if len(matches) > 1 && matches[1] != "" {
 		// Extract the memory limit
+		// Larger limits would overflow when converted to bytes
 		limit, err := strconv.Atoi(matches[1])
-		if err != nil || limit <= 0 {
-			http.Error(w, "Invalid memory limit", http.StatusBadRequest)
+		if err != nil || limit <= 0 || int64(limit) > math.MaxInt64/bytesPerMB {
+			http.Error(w, fmt.Sprintf("Invalid memory limit, must be a number of MB from 1 to %d", int64(math.MaxInt64/bytesPerMB)),
+				http.StatusBadRequest)
 			return
 		}
 		memoryLimit = limit
 	}
 
-	if !benchmark.StartMemoryTaskWithLimit(memoryLimit) {
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprintf(w, "Memory benchmark task is already running")
+	// Optional growth: ?rate=R allocates R MB per second (or everything at once with
+	// ?rate=immediate), ?block=B allocates in blocks of B MB (fractions allowed, e.g. 0.004)
+	options := benchmark.MemoryTaskOptions{LimitMB: memoryLimit}
+	query := r.URL.Query()
+
+	// Optional ?quota=F sizes the limit as share F of the cgroup memory limit, or of the host memory without one
+	quota, ok := parseQuotaParam(w, r)
+	if !ok {
+		return
+	}
+	if quota > 0 && memoryLimit > 0 {
+		http.Error(w, "Specify either a memory limit or quota", http.StatusBadRequest)
+		return
+	}
+	options.Quota = quota
+
+	if value := query.Get("rate"); value == "immediate" {
+		options.Immediate = true
+	} else if value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			http.Error(w, "Invalid rate, must be a positive number of MB per second or \"immediate\"", http.StatusBadRequest)
+			return
+		}
+		options.RateMBps = rate
+	}
+	if value := query.Get("block"); value != "" {
+		blockMB, err := strconv.ParseFloat(value, 64)
+		if err != nil || blockMB <= 0 {
+			http.Error(w, "Invalid block size, must be a positive number of MB", http.StatusBadRequest)
+			return
+		}
+		options.BlockSize = int(blockMB * 1024 * 1024)
+	}
+
+	// Optional ?seed=N makes the fill pattern of the blocks reproducible, a random seed is used otherwise
+	if value := query.Get("seed"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seed <= 0 {
+			http.Error(w, "Invalid seed, must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		options.Seed = seed
+	}
+
+	// Optional ?backend=mmap allocates outside the Go heap, where ?madvise=hugepage|nohugepage
+	// sets the transparent huge page advice and ?lock=true locks the blocks in memory
+	switch value := query.Get("backend"); value {
+	case "", benchmark.BackendHeap:
+	case benchmark.BackendMmap:
+		options.Backend = value
+	default:
+		http.Error(w, "Invalid backend, must be heap or mmap", http.StatusBadRequest)
+		return
+	}
+	switch value := query.Get("madvise"); value {
+	case benchmark.HugePagesDefault:
+	case benchmark.HugePagesOn, benchmark.HugePagesOff:
+		options.HugePages = value
+	default:
+		http.Error(w, "Invalid madvise, must be hugepage or nohugepage", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("lock"); value != "" {
+		lock, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid lock flag", http.StatusBadRequest)
+			return
+		}
+		options.Lock = lock
+	}
+	if options.Backend != benchmark.BackendMmap && (options.HugePages != benchmark.HugePagesDefault || options.Lock) {
+		http.Error(w, "madvise and lock require backend=mmap", http.StatusBadRequest)
 		return
 	}
 
-	// Get the actual memory limit being used
-	limit := benchmark.GetMaxMemoryMB()
+	// Optional ?cgroup_guard=P stops growing once the cgroup uses P percent of its memory limit
+	if value := query.Get("cgroup_guard"); value != "" {
+		guard, err := strconv.ParseFloat(value, 64)
+		if err != nil || guard <= 0 || guard > 100 {
+			http.Error(w, "Invalid cgroup guard, must be a percentage in (0, 100]", http.StatusBadRequest)
+			return
+		}
+		options.CgroupGuard = guard
+	}
+
+	// Optional hot working set: ?hot=F keeps fraction F of the blocks active,
+	// touching ?touch_rate=R MB of pages per second with ?touch=read (default) or ?touch=write
+	if value := query.Get("hot"); value != "" {
+		fraction, err := strconv.ParseFloat(value, 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			http.Error(w, "Invalid hot fraction, must be in (0, 1]", http.StatusBadRequest)
+			return
+		}
+		options.HotFraction = fraction
+	}
+	if value := query.Get("touch_rate"); value != "" {
+		touchRate, err := strconv.ParseFloat(value, 64)
+		if err != nil || touchRate <= 0 {
+			http.Error(w, "Invalid touch rate, must be a positive number of MB per second", http.StatusBadRequest)
+			return
+		}
+		options.TouchRateMBps = touchRate
+	}
+	switch query.Get("touch") {
+	case "", "read":
+	case "write":
+		options.TouchWrite = true
+	default:
+		http.Error(w, "Invalid touch mode, must be read or write", http.StatusBadRequest)
+		return
+	}
+
+	// Optional timed run: ?duration=D stops the task automatically,
+	// ?free=true additionally releases the memory when it does
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
+		return
+	}
+	options.Duration = duration
+	if value := query.Get("free"); value != "" {
+		freeAfter, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid free flag", http.StatusBadRequest)
+			return
+		}
+		options.FreeAfter = freeAfter
+	}
+	leaseTTL, ok := parseLeaseParam(w, r)
+	if !ok {
+		return
+	}
+	options.LeaseTTL = leaseTTL
+
+	jobID, err := benchmark.StartMemoryTaskWithOptions(options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	job, _ := benchmark.GetJob(jobID)
+
+	setJobHeaders(w, jobID)
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Memory benchmark task activated successfully with %d MB limit", limit)
+	fmt.Fprintf(w, "Memory benchmark job %s activated successfully with %v MB limit", jobID, job.Params["limit_mb"])
+	if quota > 0 {
+		fmt.Fprintf(w, " (%.0f%% of the memory limit)", quota*100)
+	}
+	if options.Immediate {
+		fmt.Fprintf(w, ", allocated immediately")
+	} else {
+		fmt.Fprintf(w, ", growing at %v MB/s", job.Params["rate_mbps"])
+	}
+	fmt.Fprintf(w, " in %v KB blocks", job.Params["block_size_kb"])
+	if options.Backend == benchmark.BackendMmap {
+		fmt.Fprintf(w, " mapped outside the Go heap")
+		if options.HugePages != benchmark.HugePagesDefault {
+			fmt.Fprintf(w, " with MADV_%s", strings.ToUpper(options.HugePages))
+		}
+		if options.Lock {
+			fmt.Fprintf(w, ", locked in memory")
+		}
+	}
+	if options.CgroupGuard > 0 {
+		fmt.Fprintf(w, ", stopping at %.0f%% of the cgroup memory limit", options.CgroupGuard)
+	}
+	if options.HotFraction > 0 {
+		fmt.Fprintf(w, ", keeping %.0f%% of it hot", options.HotFraction*100)
+	}
+	if duration > 0 {
+		fmt.Fprintf(w, " for %s", duration)
+		if options.FreeAfter {
+			fmt.Fprintf(w, ", memory is freed afterwards")
+		}
+	}
+	if job.LeaseID != "" {
+		fmt.Fprintf(w, " with lease %s (TTL %s)", job.LeaseID, leaseTTL)
+	}
 }
 
-// DeactivateMemoryHandler handles memory benchmark deactivation requests
+// DeactivateMemoryHandler handles memory benchmark deactivation requests by stopping all memory jobs
+// Single jobs are stopped through /jobs/{id}/stop
 func DeactivateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -136,16 +433,24 @@ func DeactivateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	allocatedMB := benchmark.GetAllocatedMemoryMB()
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Memory benchmark task deactivated successfully. %d MB still allocated - use /memory/free to release.", allocatedMB)
+	fmt.Fprintf(w, "Memory benchmark jobs deactivated successfully. %d MB still allocated - use /memory/free to release.", allocatedMB)
 }
 
 // FreeMemoryHandler explicitly forces memory cleanup
+// With ?job=ID only the memory allocated by that job is released
 func FreeMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	if jobID := r.URL.Query().Get("job"); jobID != "" {
+		releasedMB := benchmark.FreeJobMemory(jobID)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Forced memory cleanup of job %s completed. %d MB has been released back to the system.", jobID, releasedMB)
+		return
+	}
+
 	// Get memory allocation before cleanup
 	allocatedMB := benchmark.GetAllocatedMemoryMB()
 
@@ -156,7 +461,106 @@ func FreeMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Forced memory cleanup completed. %d MB has been released back to the system.", allocatedMB)
 }
 
-// StatusHandler provides information about running benchmark tasks
+// ReleaseMemoryHandler gives back part of the allocated memory
+// Supports POST /memory/release?mb=N to release N MB or ?target=N to shrink to N MB,
+// of a single job with ?job=ID or of the most recent allocations of all jobs otherwise.
+// Optional ?rate=R releases gradually at R MB/s as a job, ?free_os=true calls debug.FreeOSMemory.
+func ReleaseMemoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.ReleaseOptions{Owner: query.Get("job")}
+	current := benchmark.GetAllocatedMemoryMB()
+	if options.Owner != "" {
+		current = benchmark.GetJobMemoryMB(options.Owner)
+	}
+
+	releaseValue, targetValue := query.Get("mb"), query.Get("target")
+	switch {
+	case releaseValue != "" && targetValue == "":
+		release, err := strconv.Atoi(releaseValue)
+		if err != nil || release <= 0 {
+			http.Error(w, "Invalid amount, must be a positive number of MB", http.StatusBadRequest)
+			return
+		}
+		options.TargetMB = current - release
+		if options.TargetMB < 0 {
+			options.TargetMB = 0
+		}
+	case targetValue != "" && releaseValue == "":
+		target, err := strconv.Atoi(targetValue)
+		if err != nil || target < 0 {
+			http.Error(w, "Invalid target, must be a non-negative number of MB", http.StatusBadRequest)
+			return
+		}
+		options.TargetMB = target
+	default:
+		http.Error(w, "Specify either mb or target", http.StatusBadRequest)
+		return
+	}
+
+	if value := query.Get("rate"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			http.Error(w, "Invalid rate, must be a positive number of MB per second", http.StatusBadRequest)
+			return
+		}
+		options.RateMBps = rate
+	}
+	if value := query.Get("free_os"); value != "" {
+		freeOS, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid free_os flag", http.StatusBadRequest)
+			return
+		}
+		options.FreeOSMemory = freeOS
+	}
+
+	if options.TargetMB >= current {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "Nothing to release, %d MB allocated", current)
+		return
+	}
+
+	// A gradual release runs as a job, so it can be followed and stopped like any other
+	if options.RateMBps > 0 {
+		jobID, err := benchmark.StartMemoryRelease(options)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		setJobHeaders(w, jobID)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Memory release job %s started: shrinking from %d MB to %d MB at %.1f MB/s",
+			jobID, current, options.TargetMB, options.RateMBps)
+		return
+	}
+
+	result := benchmark.ReleaseMemory(options)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Released %d MB of memory, %d MB remain allocated.\n", result.ReleasedMB, result.AllocatedMB)
+	fmt.Fprintf(w, "Heap before: %.1f MB in use, %.1f MB idle, %.1f MB returned to the OS\n",
+		result.Before.InUseMB, result.Before.IdleMB, result.Before.ReleasedMB)
+	fmt.Fprintf(w, "Heap after: %.1f MB in use, %.1f MB idle, %.1f MB returned to the OS\n",
+		result.After.InUseMB, result.After.IdleMB, result.After.ReleasedMB)
+}
+
+// VerifyMemoryHandler checks the allocated memory against the checksums taken when it was allocated,
+// e.g. after a checkpoint/restore or live migration of the container
+// Supports GET /memory/verify with optional ?job=ID to verify only the memory of one job
+func VerifyMemoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.VerifyMemory(r.URL.Query().Get("job")))
+}
+
+// StatusHandler provides information about running benchmark jobs
 func StatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -171,30 +575,141 @@ func StatusHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "- CPU Benchmark: %s", statusText(cpuActive))
 
 	if cpuActive {
-		cores := benchmark.GetCPUCoresUsed()
-		fmt.Fprintf(w, " (using %d cores)", cores)
+		fmt.Fprintf(w, " (using %d cores - requested load %.2f cores, achieved load %.2f cores)",
+			benchmark.GetCPUCoresUsed(), benchmark.GetCPULoad(), benchmark.GetAchievedCPULoad())
 	}
 	fmt.Fprintf(w, "\n")
 
-	// Always show memory info since memory can be allocated even when the task is not running
+	if benchmark.IsCalibrating() {
+		fmt.Fprintf(w, "- CPU Calibration: RUNNING\n")
+	}
+	if benchmark.IsMeasuringLatency() {
+		fmt.Fprintf(w, "- Memory Latency Sweep: RUNNING\n")
+	}
+	writeCgroupStatus(w)
+	writeCgroupActivity(w)
+
+	// Always show memory info since memory can be allocated even when no job is running
 	allocatedMB := benchmark.GetAllocatedMemoryMB()
 	fmt.Fprintf(w, "- Memory Benchmark: %s", statusText(memoryActive))
 
 	if allocatedMB > 0 {
-		maxMB := benchmark.GetMaxMemoryMB()
-		percentage := 0
-		if maxMB > 0 {
-			percentage = allocatedMB * 100 / maxMB
-		}
-
 		if memoryActive {
-			fmt.Fprintf(w, " (using %d MB of %d MB limit - %d%%)",
-				allocatedMB, maxMB, percentage)
+			fmt.Fprintf(w, " (%d MB allocated in total", allocatedMB)
 		} else {
-			fmt.Fprintf(w, " (stopped, but still holding %d MB of memory)", allocatedMB)
+			fmt.Fprintf(w, " (stopped, but still holding %d MB of memory", allocatedMB)
+		}
+		if mappedMB := benchmark.GetMappedMemoryMB(); mappedMB > 0 {
+			fmt.Fprintf(w, ", %d MB of it mapped outside the Go heap", mappedMB)
 		}
+		fmt.Fprintf(w, ")")
+	}
+	fmt.Fprintf(w, "\n")
+	writeMemoryAccounting(w)
+
+	// Every running job with its own progress
+	fmt.Fprintf(w, "- Jobs: %d running\n", benchmark.CountRunningJobs(""))
+	for _, job := range benchmark.ListJobs() {
+		if job.State.Active() {
+			writeJobStatus(w, job)
+		}
+	}
+}
+
+// CPUResultsHandler returns the throughput results of a CPU job as JSON
+// Supports ?job=ID, by default the most recently started job generating CPU load is reported
+func CPUResultsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	results, ok := benchmark.GetCPUResults(r.URL.Query().Get("job"))
+	if !ok {
+		http.Error(w, "No CPU benchmark results available", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// EventsHandler returns the benchmark event history as JSON
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.GetEvents())
+}
+
+// parseDurationParam parses the optional ?duration= query parameter in Go duration syntax.
+// Writes a 400 response and returns false if the value is invalid.
+func parseDurationParam(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("duration")
+	if value == "" {
+		return 0, true
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		http.Error(w, "Invalid duration, must be a positive Go duration such as 30s or 5m", http.StatusBadRequest)
+		return 0, false
+	}
+	return duration, true
+}
+
+// writeJSON writes a value as an indented JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// writeLeaseStatus appends the lease expiry of a job to a status line
+func writeLeaseStatus(w http.ResponseWriter, leaseID string) {
+	if lease, ok := benchmark.GetLease(leaseID); ok {
+		fmt.Fprintf(w, " - lease %s expires in %s", lease.ID, time.Until(lease.ExpiresAt).Round(time.Second))
+	}
+}
+
+// writeMemoryAccounting writes the logical allocation next to the memory the process,
+// the Go runtime and the cgroup actually use
+func writeMemoryAccounting(w http.ResponseWriter) {
+	usage := benchmark.GetMemoryAccounting()
+
+	fmt.Fprintf(w, "- Memory Usage:\n")
+	fmt.Fprintf(w, "  - Allocated by jobs: %d MB", usage.AllocatedBytes/bytesPerMB)
+	if usage.MappedBytes > 0 {
+		fmt.Fprintf(w, " (%d MB mapped outside the Go heap)", usage.MappedBytes/bytesPerMB)
 	}
 	fmt.Fprintf(w, "\n")
+	if usage.RSSBytes > 0 {
+		fmt.Fprintf(w, "  - Process RSS: %d MB (peak %d MB, %d MB anonymous, %d MB file)\n",
+			usage.RSSBytes/bytesPerMB, usage.RSSPeakBytes/bytesPerMB, usage.RSSAnonBytes/bytesPerMB, usage.RSSFileBytes/bytesPerMB)
+	}
+	fmt.Fprintf(w, "  - Go runtime: %d MB mapped (heap objects %d MB, unused %d MB, free %d MB, released %d MB, stacks %d MB, GC goal %d MB)\n",
+		usage.RuntimeTotalBytes/bytesPerMB, usage.HeapObjectsBytes/bytesPerMB, usage.HeapUnusedBytes/bytesPerMB,
+		usage.HeapFreeBytes/bytesPerMB, usage.HeapReleasedBytes/bytesPerMB, usage.StacksBytes/bytesPerMB, usage.HeapGoalBytes/bytesPerMB)
+	settings := benchmark.GetRuntimeSettings()
+	gcPercent, memoryLimit := strconv.Itoa(settings.GCPercent), "none"
+	if settings.GCPercent < 0 {
+		gcPercent = "off"
+	}
+	if settings.MemoryLimitBytes > 0 {
+		memoryLimit = fmt.Sprintf("%d MB", settings.MemoryLimitBytes/bytesPerMB)
+	}
+	fmt.Fprintf(w, "  - Go runtime settings: GOGC %s, soft memory limit %s, GOMAXPROCS %d of %d CPUs\n",
+		gcPercent, memoryLimit, settings.GOMAXPROCS, settings.NumCPU)
+	if usage.CgroupUsageBytes > 0 {
+		fmt.Fprintf(w, "  - Cgroup: %d MB", usage.CgroupUsageBytes/bytesPerMB)
+		if usage.CgroupLimitBytes > 0 {
+			fmt.Fprintf(w, " of %d MB", usage.CgroupLimitBytes/bytesPerMB)
+		}
+		fmt.Fprintf(w, " (whole container, including page cache)\n")
+	}
 }
 
 // Helper function to convert boolean to status text
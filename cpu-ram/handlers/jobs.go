@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"benchmarking/benchmark"
+)
+
+// URL pattern for inspecting and stopping a single job
+var jobPattern = regexp.MustCompile(`^/jobs/([0-9a-zA-Z.-]+)(/stop)?$`)
+
+// JobsHandler lists all running and recently finished jobs as JSON
+// Supports GET /jobs with an optional ?kind= filter
+func JobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	jobs := make([]benchmark.JobInfo, 0)
+	for _, job := range benchmark.ListJobs() {
+		if kind == "" || job.Kind == kind {
+			jobs = append(jobs, job)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// JobHandler inspects or stops a single job
+// Supports GET /jobs/{id}, and POST /jobs/{id}/stop or DELETE /jobs/{id} to stop it
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	matches := jobPattern.FindStringSubmatch(r.URL.Path)
+	if len(matches) < 2 {
+		http.Error(w, "Missing job ID", http.StatusBadRequest)
+		return
+	}
+	id, stop := matches[1], matches[2] != ""
+
+	switch {
+	case r.Method == http.MethodGet && !stop:
+		job, ok := benchmark.GetJob(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Job %s does not exist", id), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+
+	case (r.Method == http.MethodPost && stop) || (r.Method == http.MethodDelete && !stop):
+		if _, ok := benchmark.GetJob(id); !ok {
+			http.Error(w, fmt.Sprintf("Job %s does not exist", id), http.StatusNotFound)
+			return
+		}
+		if !benchmark.StopJob(id) {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, "Job %s is not running", id)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Job %s stopped successfully", id)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// setJobHeaders exposes the ID and lease of a freshly started job to the client
+func setJobHeaders(w http.ResponseWriter, jobID string) {
+	w.Header().Set("X-Job-ID", jobID)
+	if job, ok := benchmark.GetJob(jobID); ok {
+		setLeaseHeaders(w, job.LeaseID)
+	}
+}
+
+// selectCPUJob resolves the optional ?job= parameter of CPU endpoints.
+// Without it the only running job with CPU load is used; writes an error response
+// and returns false if there is none or the choice is ambiguous.
+func selectCPUJob(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if id := r.URL.Query().Get("job"); id != "" {
+		return id, true
+	}
+
+	var running []string
+	for _, kind := range []string{benchmark.JobKindCPU, benchmark.JobKindProfile, benchmark.JobKindTrace} {
+		running = append(running, benchmark.JobIDs(kind)...)
+	}
+
+	switch len(running) {
+	case 0:
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No CPU benchmark task is currently running")
+		return "", false
+	case 1:
+		return running[0], true
+	default:
+		http.Error(w, fmt.Sprintf("%d jobs are generating CPU load, select one with ?job=", len(running)), http.StatusBadRequest)
+		return "", false
+	}
+}
+
+// writeJobStatus writes the status line of a running job
+func writeJobStatus(w http.ResponseWriter, job benchmark.JobInfo) {
+	fmt.Fprintf(w, "  - %s (%s): %s", job.ID, job.Kind, job.Summary)
+	if job.RemainingSeconds != nil {
+		remaining := time.Duration(*job.RemainingSeconds * float64(time.Second))
+		fmt.Fprintf(w, " - %s remaining", remaining.Round(time.Second))
+	}
+	writeLeaseStatus(w, job.LeaseID)
+	fmt.Fprintf(w, "\n")
+	if job.Cgroup != nil {
+		fmt.Fprintf(w, "      cgroup over %s: %s\n",
+			time.Duration(job.Cgroup.Seconds*float64(time.Second)).Round(time.Second), formatCgroupDelta(*job.Cgroup))
+	}
+}
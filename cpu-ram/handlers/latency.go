@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"benchmarking/benchmark"
+)
+
+// maxLatencyDuration bounds the time spent on each size, the sweep runs within the request
+const maxLatencyDuration = 5 * time.Second
+
+// LatencyHandler returns the most recent latency sweep (GET) or runs a new one (POST) as JSON.
+// POST supports ?sizes=KB[,KB...] or a power-of-two sweep with ?min=KB&max=KB, and ?duration=D per size (default 200ms).
+// Without sizes, powers of two from 4 KB to 256 MB are measured.
+func LatencyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		result, ok := benchmark.GetLatency()
+		if !ok {
+			http.Error(w, "No latency sweep has run yet, start one with POST", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.LatencyOptions{}
+	if value := query.Get("sizes"); value != "" {
+		if query.Get("min") != "" || query.Get("max") != "" {
+			http.Error(w, "Specify either sizes or min and max", http.StatusBadRequest)
+			return
+		}
+		for _, field := range strings.Split(value, ",") {
+			size, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || size <= 0 {
+				http.Error(w, "Invalid sizes, must be a comma-separated list of positive sizes in KB", http.StatusBadRequest)
+				return
+			}
+			options.SizesKB = append(options.SizesKB, size)
+		}
+	} else if query.Get("min") != "" || query.Get("max") != "" {
+		minKB, errMin := strconv.Atoi(query.Get("min"))
+		maxKB, errMax := strconv.Atoi(query.Get("max"))
+		if errMin != nil || errMax != nil || minKB <= 0 || maxKB < minKB {
+			http.Error(w, "Invalid min and max, both must be sizes in KB with min <= max", http.StatusBadRequest)
+			return
+		}
+		for size := minKB; size <= maxKB && len(options.SizesKB) <= benchmark.MaxLatencySizes; size *= 2 {
+			options.SizesKB = append(options.SizesKB, size)
+		}
+	}
+	if len(options.SizesKB) > benchmark.MaxLatencySizes {
+		http.Error(w, fmt.Sprintf("Too many sizes, at most %d can be measured at once", benchmark.MaxLatencySizes),
+			http.StatusBadRequest)
+		return
+	}
+
+	duration, ok := parseDurationParam(w, r)
+	if !ok {
+		return
+	}
+	if duration > maxLatencyDuration {
+		http.Error(w, fmt.Sprintf("Invalid duration, at most %s per size", maxLatencyDuration), http.StatusBadRequest)
+		return
+	}
+	options.Duration = duration
+
+	result, err := benchmark.MeasureLatency(options)
+	if errors.Is(err, benchmark.ErrLatencyBusy) || errors.Is(err, benchmark.ErrLatencyMemory) ||
+		errors.Is(err, benchmark.ErrCalibrating) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"benchmarking/benchmark"
+)
+
+// URL pattern for lease renewal
+var leaseRenewPattern = regexp.MustCompile(`^/lease/renew/([0-9a-zA-Z.]+)$`)
+
+// parseLeaseParam parses the optional ?lease= query parameter holding the lease TTL.
+// Writes a 400 response and returns false if the value is invalid.
+func parseLeaseParam(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("lease")
+	if value == "" {
+		return 0, true
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		http.Error(w, "Invalid lease TTL, must be a positive Go duration such as 30s", http.StatusBadRequest)
+		return 0, false
+	}
+	return ttl, true
+}
+
+// setLeaseHeaders exposes the lease of a freshly activated task to the client
+func setLeaseHeaders(w http.ResponseWriter, leaseID string) {
+	if lease, ok := benchmark.GetLease(leaseID); ok {
+		w.Header().Set("X-Lease-ID", lease.ID)
+		w.Header().Set("X-Lease-TTL", lease.TTL.String())
+	}
+}
+
+// RenewLeaseHandler extends a lease by its TTL
+// Supports POST /lease/renew/{id}
+func RenewLeaseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	matches := leaseRenewPattern.FindStringSubmatch(r.URL.Path)
+	if len(matches) < 2 {
+		http.Error(w, "Missing lease ID", http.StatusBadRequest)
+		return
+	}
+
+	lease, ok := benchmark.RenewLease(matches[1])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Lease %s does not exist or has already expired", matches[1])
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Lease %s renewed for %s", lease.ID, lease.TTL)
+}
+
+// LeasesHandler returns all active leases as JSON
+func LeasesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.GetLeases())
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"benchmarking/benchmark"
+)
+
+// cgroupMetricHelp describes the cgroup statistics exposed as metrics
+var cgroupMetricHelp = map[string]string{
+	"cpu_usage_usec":                 "CPU time used by the cgroup in microseconds",
+	"cpu_periods":                    "CFS enforcement periods that elapsed while the cgroup was runnable",
+	"cpu_throttled_periods":          "CFS periods in which the cgroup was throttled",
+	"cpu_throttled_usec":             "Time the cgroup was throttled in microseconds",
+	"memory_events_high":             "Times the cgroup was throttled and reclaimed above memory.high",
+	"memory_events_max":              "Times the cgroup usage was about to exceed memory.max (memory.failcnt with cgroup v1)",
+	"memory_events_oom":              "Times the cgroup hit its limit and reclaim failed",
+	"memory_events_oom_kill":         "Processes of the cgroup killed by the OOM killer",
+	"memory_pgfault":                 "Page faults of the cgroup",
+	"memory_pgmajfault":              "Major page faults of the cgroup",
+	"memory_pgscan":                  "Pages scanned by reclaim",
+	"memory_pgsteal":                 "Pages reclaimed",
+	"memory_workingset_refault_anon": "Refaults of previously evicted anonymous pages",
+	"memory_workingset_refault_file": "Refaults of previously evicted file pages",
+	"memory_current_bytes":           "Memory used by the cgroup in bytes, including page cache",
+	"memory_anon_bytes":              "Anonymous memory of the cgroup in bytes",
+	"memory_file_bytes":              "Page cache of the cgroup in bytes",
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricWriter writes metrics in the Prometheus text exposition format
+type metricWriter struct {
+	w io.Writer
+}
+
+// family starts a metric family with its help text and type (counter or gauge)
+func (m metricWriter) family(name, kind, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one value of a family, labeled with a job ID if given.
+// The label is job_id, since Prometheus attaches its own job label to every scraped series.
+func (m metricWriter) sample(name, job string, value float64) {
+	if job != "" {
+		name += `{job_id="` + labelEscaper.Replace(job) + `"}`
+	}
+	fmt.Fprintf(m.w, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// single writes a metric family with one unlabeled value
+func (m metricWriter) single(name, kind, help string, value float64) {
+	m.family(name, kind, help)
+	m.sample(name, "", value)
+}
+
+// counterNames returns the names of the cgroup counters in a stable order
+func counterNames(stats benchmark.CgroupStats) []string {
+	names := make([]string, 0, len(stats.Counters))
+	for name := range stats.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gaugeNames returns the names of the cgroup gauges in a stable order
+func gaugeNames(stats benchmark.CgroupStats) []string {
+	names := make([]string, 0, len(stats.Gauges))
+	for name := range stats.Gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricsHandler exposes the process, cgroup and per-job measurements in the Prometheus text format
+// Supports GET /metrics
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	m := metricWriter{w: w}
+
+	// Generated load
+	m.single("benchmark_jobs_running", "gauge", "Running benchmark jobs", float64(benchmark.CountRunningJobs("")))
+	m.single("benchmark_cpu_requested_load_cores", "gauge", "CPU load requested by all jobs in cores", benchmark.GetCPULoad())
+	m.single("benchmark_cpu_achieved_load_cores", "gauge", "CPU load delivered by the CPU workers in cores", benchmark.GetAchievedCPULoad())
+	m.single("benchmark_memory_allocated_bytes", "gauge", "Memory allocated by all jobs in bytes", float64(benchmark.GetAllocatedMemoryBytes()))
+
+	// Logical allocation next to the memory the process and the Go runtime actually use
+	usage := benchmark.GetMemoryAccounting()
+	m.single("benchmark_memory_mapped_bytes", "gauge", "Part of the allocated memory mapped outside the Go heap in bytes", float64(usage.MappedBytes))
+	m.single("benchmark_process_resident_bytes", "gauge", "Resident memory of the process (VmRSS) in bytes", float64(usage.RSSBytes))
+	m.single("benchmark_process_resident_peak_bytes", "gauge", "Highest resident memory of the process (VmHWM) in bytes", float64(usage.RSSPeakBytes))
+	m.single("benchmark_process_resident_anon_bytes", "gauge", "Resident anonymous memory of the process (RssAnon) in bytes", float64(usage.RSSAnonBytes))
+	m.single("benchmark_process_resident_file_bytes", "gauge", "Resident file mappings of the process (RssFile) in bytes", float64(usage.RSSFileBytes))
+	m.single("benchmark_go_heap_objects_bytes", "gauge", "Go heap memory occupied by live objects and dead ones not yet swept", float64(usage.HeapObjectsBytes))
+	m.single("benchmark_go_heap_unused_bytes", "gauge", "Go heap memory reserved for objects but currently empty", float64(usage.HeapUnusedBytes))
+	m.single("benchmark_go_heap_free_bytes", "gauge", "Free Go heap memory not yet returned to the OS", float64(usage.HeapFreeBytes))
+	m.single("benchmark_go_heap_released_bytes", "gauge", "Free Go heap memory returned to the OS", float64(usage.HeapReleasedBytes))
+	m.single("benchmark_go_stacks_bytes", "gauge", "Memory of goroutine stacks", float64(usage.StacksBytes))
+	m.single("benchmark_go_memory_total_bytes", "gauge", "All memory mapped by the Go runtime", float64(usage.RuntimeTotalBytes))
+	m.single("benchmark_go_heap_goal_bytes", "gauge", "Heap size at which the next garbage collection is triggered", float64(usage.HeapGoalBytes))
+	settings := benchmark.GetRuntimeSettings()
+	m.single("benchmark_go_gc_percent", "gauge", "GOGC, -1 if the garbage collector is off", float64(settings.GCPercent))
+	m.single("benchmark_go_memory_limit_bytes", "gauge", "Soft memory limit of the Go runtime in bytes, 0 if there is none", float64(settings.MemoryLimitBytes))
+	m.single("benchmark_go_gomaxprocs", "gauge", "GOMAXPROCS", float64(settings.GOMAXPROCS))
+
+	// Limits and current statistics of the container
+	limits := benchmark.GetCgroupLimits()
+	m.single("benchmark_cgroup_cpu_limit_cores", "gauge", "CPU cores the container may use", limits.CPULimit)
+	m.single("benchmark_cgroup_memory_limit_bytes", "gauge", "Cgroup memory limit in bytes, 0 if unlimited", float64(limits.MemoryLimitMB*bytesPerMB))
+
+	stats := benchmark.GetCgroupStats()
+	for _, name := range counterNames(stats) {
+		metric := "benchmark_cgroup_" + name + "_total"
+		m.family(metric, "counter", cgroupMetricHelp[name])
+		m.sample(metric, "", float64(stats.Counters[name]))
+	}
+	for _, name := range gaugeNames(stats) {
+		metric := "benchmark_cgroup_" + name
+		m.family(metric, "gauge", cgroupMetricHelp[name])
+		m.sample(metric, "", float64(stats.Gauges[name]))
+	}
+
+	// Per job: whether it runs and how the cgroup statistics changed while it did
+	jobs := benchmark.ListJobs()
+	m.family("benchmark_job_running", "gauge", "Whether the job is running")
+	for _, job := range jobs {
+		running := 0.0
+		if job.State.Active() {
+			running = 1
+		}
+		m.sample("benchmark_job_running", job.ID, running)
+	}
+	for _, name := range counterNames(stats) {
+		metric := "benchmark_job_cgroup_" + name + "_total"
+		m.family(metric, "counter", cgroupMetricHelp[name]+" while the job ran")
+		for _, job := range jobs {
+			if job.Cgroup != nil {
+				m.sample(metric, job.ID, float64(job.Cgroup.Counters[name]))
+			}
+		}
+	}
+	m.family("benchmark_job_cgroup_memory_change_bytes", "gauge", "Change of the cgroup memory usage while the job ran")
+	for _, job := range jobs {
+		if job.Cgroup != nil {
+			m.sample("benchmark_job_cgroup_memory_change_bytes", job.ID, float64(job.Cgroup.MemoryChangeBytes))
+		}
+	}
+	m.family("benchmark_job_cgroup_memory_peak_bytes", "gauge", "Highest cgroup memory usage sampled while the job ran")
+	for _, job := range jobs {
+		if job.Cgroup != nil {
+			m.sample("benchmark_job_cgroup_memory_peak_bytes", job.ID, float64(job.Cgroup.MemoryPeakBytes))
+		}
+	}
+
+	// Bandwidth of the most recent round of each bandwidth job, per kernel
+	bandwidth := make(map[string]benchmark.BandwidthResults)
+	for _, job := range jobs {
+		if job.Kind == benchmark.JobKindBandwidth {
+			if results, ok := benchmark.GetBandwidthResults(job.ID); ok && results.Rounds > 0 {
+				bandwidth[job.ID] = results
+			}
+		}
+	}
+	for i, kernel := range benchmark.StreamKernelNames() {
+		metric := "benchmark_job_bandwidth_" + kernel + "_gbps"
+		m.family(metric, "gauge", "Memory bandwidth of the STREAM "+kernel+" kernel in the job's last round, in GB/s")
+		for _, job := range jobs {
+			if results, ok := bandwidth[job.ID]; ok {
+				m.sample(metric, job.ID, results.Kernels[i].LastGBps)
+			}
+		}
+	}
+
+	// GC pauses and heap overhead seen by each churn job
+	churn := make(map[string]benchmark.ChurnResults)
+	for _, job := range jobs {
+		if job.Kind == benchmark.JobKindChurn {
+			if results, ok := benchmark.GetChurnResults(job.ID); ok && results.Filled {
+				churn[job.ID] = results
+			}
+		}
+	}
+	m.family("benchmark_job_churn_gc_pause_p99_seconds", "gauge", "99th percentile of the GC pauses since the churn job started")
+	for _, job := range jobs {
+		if results, ok := churn[job.ID]; ok {
+			m.sample("benchmark_job_churn_gc_pause_p99_seconds", job.ID, results.GCPauseP99Ms/1000)
+		}
+	}
+	m.family("benchmark_job_churn_gc_pause_max_seconds", "gauge", "Longest GC pause since the churn job started")
+	for _, job := range jobs {
+		if results, ok := churn[job.ID]; ok {
+			m.sample("benchmark_job_churn_gc_pause_max_seconds", job.ID, results.GCPauseMaxMs/1000)
+		}
+	}
+	m.family("benchmark_job_churn_heap_overhead_percent", "gauge", "Heap memory held beyond the live set of the churn job, relative to it")
+	for _, job := range jobs {
+		if results, ok := churn[job.ID]; ok {
+			m.sample("benchmark_job_churn_heap_overhead_percent", job.ID, results.OverheadPct)
+		}
+	}
+}
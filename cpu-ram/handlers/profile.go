@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"benchmarking/benchmark"
+)
+
+// profileRequest is the JSON body accepted by /cpu/profile.
+// Period and duration use Go duration syntax (e.g. "30s", "5m").
+type profileRequest struct {
+	Type     string  `json:"type"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Period   string  `json:"period"`
+	Duration string  `json:"duration"`
+}
+
+// ProfileHandler starts a time-varying CPU load profile
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req profileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid profile: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	profile := benchmark.LoadProfile{
+		Type: req.Type,
+		Min:  req.Min,
+		Max:  req.Max,
+	}
+
+	var err error
+	if profile.Period, err = time.ParseDuration(req.Period); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid period: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Duration != "" {
+		if profile.Duration, err = time.ParseDuration(req.Duration); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid duration: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := profile.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid profile: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := benchmark.StartProfile(profile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	setJobHeaders(w, jobID)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "CPU load profile job %s started: %s between %.2f and %.2f cores with period %s",
+		jobID, profile.Type, profile.Min, profile.Max, profile.Period)
+	if profile.Duration > 0 {
+		fmt.Fprintf(w, " for %s", profile.Duration)
+	}
+}
+
+// DeactivateProfileHandler stops all running CPU load profiles
+func DeactivateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !benchmark.StopProfile() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No CPU load profile is currently running")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "CPU load profiles deactivated successfully")
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"benchmarking/benchmark"
+)
+
+// maxTraceBytes bounds the size of a trace sent as the request body
+const maxTraceBytes = 16 * bytesPerMB
+
+// TraceDir is the directory ?path= is resolved against, set from the configuration in main
+var TraceDir = "/data"
+
+// TraceHandler starts replaying a CPU and memory utilization trace.
+// The CSV trace is either sent as the request body or read from ?path= relative to the trace directory.
+// Optional query parameters: ?speedup=X (trace seconds per second) and ?loop=true
+func TraceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	options := benchmark.TraceOptions{Speedup: 1}
+	if value := query.Get("speedup"); value != "" {
+		speedup, err := strconv.ParseFloat(value, 64)
+		if err != nil || speedup <= 0 {
+			http.Error(w, "Invalid speedup, must be a positive number", http.StatusBadRequest)
+			return
+		}
+		options.Speedup = speedup
+	}
+	if value := query.Get("loop"); value != "" {
+		loop, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid loop flag", http.StatusBadRequest)
+			return
+		}
+		options.Loop = loop
+	}
+
+	var trace benchmark.Trace
+	var err error
+	if path := query.Get("path"); path != "" {
+		trace, err = benchmark.LoadTraceFile(TraceDir, path)
+	} else {
+		trace, err = benchmark.ParseTrace(http.MaxBytesReader(w, r.Body, maxTraceBytes))
+	}
+	if errors.Is(err, benchmark.ErrTraceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Trace too large, at most %d MB can be sent", maxTraceBytes/bytesPerMB), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid trace: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := benchmark.StartTrace(trace, options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	setJobHeaders(w, jobID)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Trace replay job %s started: %d samples spanning %s at %.1fx speed", jobID, len(trace), trace.Length(), options.Speedup)
+	if options.Loop {
+		fmt.Fprintf(w, " (looping)")
+	}
+}
+
+// DeactivateTraceHandler stops all running trace replays
+func DeactivateTraceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !benchmark.StopTrace() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "No trace replay is currently running")
+		return
+	}
+
+	allocatedMB := benchmark.GetAllocatedMemoryMB()
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Trace replays deactivated successfully. %d MB still allocated - use /memory/free to release.", allocatedMB)
+}
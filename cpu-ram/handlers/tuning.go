@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"benchmarking/benchmark"
+)
+
+// RuntimeHandler returns the Go runtime settings (GET) or changes them (POST) as JSON.
+// POST supports ?gogc=N|off, ?memory_limit=MB|off for the soft memory limit and ?gomaxprocs=N;
+// all given settings are validated before any is applied.
+func RuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, benchmark.GetRuntimeSettings())
+		return
+	case http.MethodPost, http.MethodPatch:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	gcValue, limitValue, procsValue := query.Get("gogc"), query.Get("memory_limit"), query.Get("gomaxprocs")
+	if gcValue == "" && limitValue == "" && procsValue == "" {
+		http.Error(w, "Specify gogc, memory_limit or gomaxprocs", http.StatusBadRequest)
+		return
+	}
+
+	gcPercent := -1 // "off"
+	if gcValue != "" && gcValue != "off" {
+		percent, err := strconv.Atoi(gcValue)
+		if err != nil || percent < 0 {
+			http.Error(w, "Invalid gogc, must be a non-negative percentage or off", http.StatusBadRequest)
+			return
+		}
+		gcPercent = percent
+	}
+	var memoryLimit int64 // "off"
+	if limitValue != "" && limitValue != "off" {
+		// Larger limits would overflow when converted to bytes
+		limitMB, err := strconv.ParseInt(limitValue, 10, 64)
+		if err != nil || limitMB <= 0 || limitMB > math.MaxInt64/bytesPerMB {
+			http.Error(w, fmt.Sprintf("Invalid memory_limit, must be a number of MB from 1 to %d or off", int64(math.MaxInt64/bytesPerMB)),
+				http.StatusBadRequest)
+			return
+		}
+		memoryLimit = limitMB * bytesPerMB
+	}
+	procs := 0
+	if procsValue != "" {
+		value, err := strconv.Atoi(procsValue)
+		if err != nil || value <= 0 {
+			http.Error(w, "Invalid gomaxprocs, must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		procs = value
+	}
+
+	if gcValue != "" {
+		benchmark.SetGCPercent(gcPercent)
+	}
+	if limitValue != "" {
+		benchmark.SetMemoryLimit(memoryLimit)
+	}
+	if procs > 0 {
+		benchmark.SetMaxProcs(procs)
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.GetRuntimeSettings())
+}
+
+// FreeOSMemoryHandler runs debug.FreeOSMemory and returns the heap before and after as JSON
+// Supports POST /runtime/free
+func FreeOSMemoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmark.FreeOSMemory())
+}
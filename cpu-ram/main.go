@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 
+	"benchmarking/benchmark"
 	"benchmarking/config"
 	"benchmarking/handlers"
 )
@@ -19,10 +20,25 @@ func main() {
 
 	// Pass version information to handlers package
 	handlers.BuildVersion = buildVersion
+	handlers.TraceDir = cfg.TraceDir
 
 	// Log version information
 	log.Printf("Starting CPU-RAM benchmarking server version %s", buildVersion)
 
+	// Log the limits of the container, activations can be sized relative to them
+	limits := benchmark.GetCgroupLimits()
+	log.Printf("Cgroup v%d limits: %.2f of %d CPUs, %d MB memory (0 = unlimited, %d MB host memory)",
+		limits.Version, limits.CPULimit, limits.CPUs, limits.MemoryLimitMB, limits.HostMemoryMB)
+
+	// Measure the idle baseline in the background so the server starts immediately
+	if cfg.CalibrateOnStartup {
+		go func() {
+			if _, err := benchmark.Calibrate(); err != nil {
+				log.Printf("Startup calibration failed: %v", err)
+			}
+		}()
+	}
+
 	// Register routes
 	http.HandleFunc("/", handlers.HelloHandler)
 	http.HandleFunc("/health", handlers.HealthCheckHandler)
@@ -31,15 +47,51 @@ func main() {
 	http.HandleFunc("/cpu/activate", handlers.ActivateHandler)
 	http.HandleFunc("/cpu/activate/", handlers.ActivateHandler) // To handle /cpu/activate/N
 	http.HandleFunc("/cpu/deactivate", handlers.DeactivateHandler)
+	http.HandleFunc("/cpu/resize", handlers.ResizeHandler)
+	http.HandleFunc("/cpu/resize/", handlers.ResizeHandler) // To handle /cpu/resize/N
+	http.HandleFunc("/cpu/results", handlers.CPUResultsHandler)
+	http.HandleFunc("/cpu/calibrate", handlers.CalibrateHandler)
+	http.HandleFunc("/cpu/profile", handlers.ProfileHandler)
+	http.HandleFunc("/cpu/profile/deactivate", handlers.DeactivateProfileHandler)
 
 	// Memory benchmark endpoints - using flexible pattern matching in the handler
 	http.HandleFunc("/memory/activate", handlers.ActivateMemoryHandler)
 	http.HandleFunc("/memory/activate/", handlers.ActivateMemoryHandler) // To handle /memory/activate/N
 	http.HandleFunc("/memory/deactivate", handlers.DeactivateMemoryHandler)
 	http.HandleFunc("/memory/free", handlers.FreeMemoryHandler) // Endpoint to explicitly free memory
+	http.HandleFunc("/memory/release", handlers.ReleaseMemoryHandler)
+	http.HandleFunc("/memory/verify", handlers.VerifyMemoryHandler)
+	http.HandleFunc("/memory/dirty", handlers.DirtyHandler)
+	http.HandleFunc("/memory/dirty/deactivate", handlers.DeactivateDirtyHandler)
+	http.HandleFunc("/memory/bandwidth", handlers.BandwidthHandler)
+	http.HandleFunc("/memory/bandwidth/deactivate", handlers.DeactivateBandwidthHandler)
+	http.HandleFunc("/memory/latency", handlers.LatencyHandler)
+	http.HandleFunc("/memory/churn", handlers.ChurnHandler)
+	http.HandleFunc("/memory/churn/deactivate", handlers.DeactivateChurnHandler)
+
+	// Trace replay endpoints - the trace drives both the CPU and memory load
+	http.HandleFunc("/trace/activate", handlers.TraceHandler)
+	http.HandleFunc("/trace/deactivate", handlers.DeactivateTraceHandler)
+
+	// Job endpoints - every activation above creates a job that can be inspected and stopped individually
+	http.HandleFunc("/jobs", handlers.JobsHandler)
+	http.HandleFunc("/jobs/", handlers.JobHandler) // To handle /jobs/{id} and /jobs/{id}/stop
 
 	// Status endpoint
 	http.HandleFunc("/status", handlers.StatusHandler)
+	http.HandleFunc("/cgroup", handlers.CgroupHandler)
+	http.HandleFunc("/metrics", handlers.MetricsHandler)
+
+	// Go runtime tuning endpoints - GOGC, soft memory limit and GOMAXPROCS can be swept between runs
+	http.HandleFunc("/runtime", handlers.RuntimeHandler)
+	http.HandleFunc("/runtime/free", handlers.FreeOSMemoryHandler)
+
+	// Lease endpoints - leased tasks stop unless renewed within their TTL
+	http.HandleFunc("/lease/renew/", handlers.RenewLeaseHandler)
+	http.HandleFunc("/leases", handlers.LeasesHandler)
+
+	// Event history endpoint
+	http.HandleFunc("/events", handlers.EventsHandler)
 
 	// Version endpoint to display container version
 	http.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {